@@ -0,0 +1,137 @@
+package accounting
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BankStatement is a statement of a bank account that ReadOFX or ReadCAMT053 read.
+type BankStatement struct {
+	Account        string      // the number or the IBAN of the account in the bank
+	Currency       string      // like "USD", empty if the file has no currency
+	ClosingBalance BankBalance // the booked balance at the end of the statement
+	Transactions   []BankTransaction
+}
+
+// BankBalance is the balance of a bank account at a time.
+type BankBalance struct {
+	TimeUnix // the balance is after the transactions until this time, zero if the statement has no balance
+	Amount   // positive for money in the account
+}
+
+// BankTransaction is a booked transaction of a bank statement.
+type BankTransaction struct {
+	ID       string   // the ID that the bank gave to the transaction like the FITID of OFX
+	TimeUnix TimeUnix // the time that the bank booked the transaction
+	Amount   Amount   // positive for money into the account and negative for money out of it
+	Payee    string   // the name of the other party
+	Memo     string   // the text of the bank about the transaction
+}
+
+// BankStatementConfig configures the draft entries of a bank statement.
+type BankStatementConfig struct {
+	BankAccountID AccountID // the account of the bank account in the journal like the USD account
+	Currency      string    // the currency of the bank account, a statement in another currency is rejected, empty to accept any
+
+	// Rules give the counter-account of a transaction, the first rule that matches it is used.
+	// A transaction that no rule matches gets the DefaultCounterAccountID,
+	// or is returned without an entry if DefaultCounterAccountID is zero.
+	Rules                   []BankRule
+	DefaultCounterAccountID AccountID
+}
+
+// BankRule is a rule that gives the counter-account of the bank transactions that it matches.
+// A zero field matches every transaction.
+type BankRule struct {
+	Text             string    // a part of the payee or of the memo, the case is ignored
+	IsMoneyIn        *bool     // true for money into the bank account, false for money out of it
+	CounterAccountID AccountID // the account of the other line of the entry
+}
+
+// Match reports whether the rule matches the transaction.
+func (rule BankRule) Match(transaction BankTransaction) bool {
+	if rule.IsMoneyIn != nil && *rule.IsMoneyIn != (transaction.Amount > 0) {
+		return false
+	}
+	text := strings.ToLower(rule.Text)
+	return strings.Contains(strings.ToLower(transaction.Payee), text) || strings.Contains(strings.ToLower(transaction.Memo), text)
+}
+
+// DraftBankEntries returns the draft entries of the transactions of the statement in the order of their times,
+// so the bookkeeping starts from the bank feed. The entries are not posted, review them and post them with AddBatch.
+//
+// Every entry has a line of the bank account with the amount as its quantity, like the money accounts of the journal,
+// and a line of the counter-account with a zero quantity. The money into the bank account is a debit of it and
+// a credit of the counter-account. A line is INFLOW if it increases its account, otherwise NONE for a zero quantity
+// and FIFO for the bank account.
+//
+// The Reference of an entry is the ID of its transaction and its Description is the payee and the memo.
+// Its IdempotencyKey is the bank account ID and the ID of the transaction like "2001:FITID-1",
+// so a statement that is imported again doesn't post its transactions again.
+//
+// Returns the entries, the transactions that no rule matches and that have no entry, or an Error with the name
+// ErrInvalidBankStatement if the statement is not in the currency of the config or has a transaction with a zero amount.
+func DraftBankEntries(statement BankStatement, config BankStatementConfig) ([]AccountingEntry, []BankTransaction, error) {
+	if config.Currency != "" && statement.Currency != "" && config.Currency != statement.Currency {
+		return nil, nil, fErrInvalidBankStatement(fmt.Sprintf("its currency %v is not the currency %v of the bank account", statement.Currency, config.Currency))
+	}
+
+	transactions := slices.Clone(statement.Transactions)
+	slices.SortStableFunc(transactions, func(a, b BankTransaction) int {
+		return cmp.Compare(a.TimeUnix, b.TimeUnix)
+	})
+
+	var entries []AccountingEntry
+	var unmatched []BankTransaction
+	for _, transaction := range transactions {
+		if transaction.Amount == 0 || math.IsNaN(float64(transaction.Amount)) {
+			return nil, nil, fErrInvalidBankStatement(fmt.Sprintf("the transaction %v has the amount %v", transaction.ID, transaction.Amount))
+		}
+
+		counterAccountID := config.DefaultCounterAccountID
+		for _, rule := range config.Rules {
+			if rule.Match(transaction) {
+				counterAccountID = rule.CounterAccountID
+				break
+			}
+		}
+		if counterAccountID == 0 {
+			unmatched = append(unmatched, transaction)
+			continue
+		}
+
+		amount := Amount(math.Abs(float64(transaction.Amount)))
+		isMoneyIn := transaction.Amount > 0
+		entry := AccountingEntry{
+			TimeUnix: transaction.TimeUnix,
+			DoubleEntry: DoubleEntry{
+				bankLine(config.BankAccountID, isMoneyIn, Quantity(amount), amount),
+				bankLine(counterAccountID, !isMoneyIn, 0, amount),
+			},
+			Metadata: Metadata{
+				Reference:   transaction.ID,
+				Description: strings.TrimSpace(transaction.Payee + " " + transaction.Memo),
+			},
+		}
+		if transaction.ID != "" {
+			entry.IdempotencyKey = fmt.Sprintf("%v:%v", config.BankAccountID, transaction.ID)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, unmatched, nil
+}
+
+// bankLine returns the line of the account that is a debit if isDebit is true.
+func bankLine(ID AccountID, isDebit bool, quantity Quantity, amount Amount) SingleEntry {
+	isIncrease := isDebit == bool(IsNatureDebit(ID))
+	return SingleEntry{defaultCostFlowType(isIncrease, quantity, amount), ID, quantity, amount}
+}
+
+// endOfDay returns the last time of the day of the time in UTC, for a balance at a date.
+func endOfDay(timeUnix TimeUnix) TimeUnix {
+	return time.UnixMicro(timeUnix).UTC().Truncate(24*time.Hour).Add(24*time.Hour).UnixMicro() - 1
+}
@@ -0,0 +1,138 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_DraftBankEntries(t *testing.T) {
+	isMoneyOut := false
+	config := BankStatementConfig{
+		BankAccountID: 2001,
+		Currency:      "USD",
+		Rules: []BankRule{
+			{Text: "acme", CounterAccountID: -4001},
+			{Text: "power", IsMoneyIn: &isMoneyOut, CounterAccountID: 3001},
+		},
+	}
+
+	type input struct {
+		statement BankStatement
+		config    BankStatementConfig
+	}
+	type output struct {
+		AccountingEntries []AccountingEntry
+		BankTransactions  []BankTransaction
+		err               error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				statement: BankStatement{
+					Currency: "USD",
+					Transactions: []BankTransaction{
+						{ID: "F-2", TimeUnix: 1709424000000000, Amount: -42.5, Payee: "Power Co"},
+						{ID: "F-1", TimeUnix: 1709393400000000, Amount: 1500, Payee: "ACME", Memo: "Invoice 17"},
+						{ID: "F-3", TimeUnix: 1709424000000000, Amount: -10, Payee: "Water Co"},
+						{ID: "F-4", TimeUnix: 1709424000000000, Amount: 20, Payee: "Power Co", Memo: "refund"},
+					},
+				},
+				config: config,
+			},
+			output: output{
+				AccountingEntries: []AccountingEntry{
+					{
+						TimeUnix:       1709393400000000,
+						DoubleEntry:    DoubleEntry{{INFLOW, 2001, 1500, 1500}, {INFLOW, -4001, 0, 1500}},
+						Metadata:       Metadata{Reference: "F-1", Description: "ACME Invoice 17"},
+						IdempotencyKey: "2001:F-1",
+					},
+					{
+						TimeUnix:       1709424000000000,
+						DoubleEntry:    DoubleEntry{{FIFO, 2001, 42.5, 42.5}, {INFLOW, 3001, 0, 42.5}},
+						Metadata:       Metadata{Reference: "F-2", Description: "Power Co"},
+						IdempotencyKey: "2001:F-2",
+					},
+				},
+				BankTransactions: []BankTransaction{
+					{ID: "F-3", TimeUnix: 1709424000000000, Amount: -10, Payee: "Water Co"},
+					{ID: "F-4", TimeUnix: 1709424000000000, Amount: 20, Payee: "Power Co", Memo: "refund"},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				statement: BankStatement{
+					Transactions: []BankTransaction{
+						{TimeUnix: 1709424000000000, Amount: 20, Payee: "Power Co", Memo: "refund"},
+					},
+				},
+				config: BankStatementConfig{BankAccountID: 2001, DefaultCounterAccountID: 3001},
+			},
+			output: output{
+				AccountingEntries: []AccountingEntry{
+					{
+						TimeUnix:    1709424000000000,
+						DoubleEntry: DoubleEntry{{INFLOW, 2001, 20, 20}, {NONE, 3001, 0, 20}},
+						Metadata:    Metadata{Description: "Power Co refund"},
+					},
+				},
+				BankTransactions: nil,
+				err:              nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				statement: BankStatement{Currency: "EUR"},
+				config:    config,
+			},
+			output: output{
+				AccountingEntries: nil,
+				BankTransactions:  nil,
+				err:               fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because its currency EUR is not the currency USD of the bank account"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				statement: BankStatement{Transactions: []BankTransaction{{ID: "F-1", Amount: 0}}},
+				config:    config,
+			},
+			output: output{
+				AccountingEntries: nil,
+				BankTransactions:  nil,
+				err:               fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because the transaction F-1 has the amount 0"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.AccountingEntries, output.BankTransactions, output.err = DraftBankEntries(tt.input.statement, tt.input.config)
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+
+	// the drafts are posted once even if the statement is imported again
+	entries, _, err := DraftBankEntries(tests[0].input.statement, config)
+	fTest(err, nil)
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+	IDs, _, err := AddBatch(entries, &kk)
+	fTest(err, nil)
+	again, _, err := AddBatch(entries, &kk)
+	fTest(err, nil)
+	fTest(again, IDs)
+	fTest(len(kk.myEntries), 2)
+	fTest(kk.myInv[2001], Inventory{{1709393400000000, 1457.5, 1457.5}})
+}
@@ -0,0 +1,169 @@
+package accounting
+
+import (
+	"maps"
+	"slices"
+)
+
+// BatchIssue describes an entry of a batch that can't be posted.
+type BatchIssue struct {
+	Index int   // the index of the entry in the batch
+	Err   error // the error that the entry gives when it is posted after the valid entries before it
+}
+
+// BatchDB is a DB that can store the entries and the inventories of a batch in one atomic write,
+// like in one transaction of a database.
+type BatchDB interface {
+	DB
+	SetEntriesAndInventories([]AccountingEntry, AccountIDAndInventory) error
+}
+
+// AddBatch adds many entries to the journal, all of them or none of them.
+//
+// Parameters:
+//   - entries: The entries to be added in their order, their times should not decrease
+//   - dbCommand: The storage of the journal and the inventories
+//
+// The entries are validated and processed in sequence like AddToJournal against an in-memory copy
+// of the inventories, so every inventory is read from the DB only once.
+// An invalid entry is skipped so the issues of all the entries after it are reported too.
+// An entry whose IdempotencyKey is already posted, in the journal or earlier in the batch,
+// gets the ID of the posted entry and is not posted again.
+//
+// The journal and the inventories are changed only if there is no issue: then the entries and the inventories
+// of all the accounts of the batch are stored with SetEntriesAndInventories if dbCommand is a BatchDB.
+// Otherwise they are stored one by one with SetEntry and SetInventory, and these writes are not atomic:
+// if one of them fails the writes before it are kept.
+//
+// Returns the IDs of the entries in the order of the batch, or the issues and an error
+// if any entry is invalid.
+func AddBatch(entries []AccountingEntry, dbCommand DB) ([]EntryID, []BatchIssue, error) {
+	batch, err := checkBatch(entries, dbCommand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(batch.issues) != 0 {
+		return nil, batch.issues, newError(ErrBatchHasInvalidEntries, "%v of the %v entries of the batch are invalid", len(batch.issues), len(entries))
+	}
+
+	if batchDB, ok := dbCommand.(BatchDB); ok {
+		err := batchDB.SetEntriesAndInventories(batch.newEntries, batch.IDAndInventory)
+		if err != nil {
+			return nil, nil, err
+		}
+		return batch.IDs, nil, nil
+	}
+
+	for _, entry := range batch.newEntries {
+		err := dbCommand.SetEntry(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for ID, inv := range batch.IDAndInventory {
+		err := dbCommand.SetInventory(ID, inv)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return batch.IDs, nil, nil
+}
+
+// checkedBatch is a batch of entries checked by checkBatch.
+type checkedBatch struct {
+	IDs            []EntryID             // the IDs of the entries in the order of the batch
+	newEntries     []AccountingEntry     // the valid entries that are not posted yet, with their IDs and hashes
+	IDAndInventory AccountIDAndInventory // the inventories of the accounts of the batch after the valid entries
+	issues         []BatchIssue
+}
+
+// checkBatch validates and processes the entries of a batch in sequence like AddToJournal against
+// an in-memory copy of the inventories, it only reads dbCommand.
+// An invalid entry is skipped so the issues of all the entries after it are found too.
+func checkBatch(entries []AccountingEntry, dbCommand DB) (checkedBatch, error) {
+	lastEntry, err := dbCommand.GetLastEntry()
+	if err != nil {
+		return checkedBatch{}, err
+	}
+
+	lastID, lastHash, err := getLastPosted(dbCommand)
+	if err != nil {
+		return checkedBatch{}, err
+	}
+
+	requiredDimensions, err := getRequiredDimensions(dbCommand)
+	if err != nil {
+		return checkedBatch{}, err
+	}
+
+	batch := checkedBatch{IDs: make([]EntryID, len(entries)), IDAndInventory: make(AccountIDAndInventory)}
+	postedByKey := make(map[string]AccountingEntry)
+	for i, entry := range entries {
+		if posted, ok := postedByKey[entry.IdempotencyKey]; ok && entry.IdempotencyKey != "" {
+			err := checkSameContent(posted, entry)
+			if err != nil {
+				batch.issues = append(batch.issues, BatchIssue{i, err})
+			}
+			batch.IDs[i] = posted.ID
+			continue
+		}
+
+		posted, ok, err := getPostedEntry(entry, dbCommand)
+		if err != nil {
+			batch.issues = append(batch.issues, BatchIssue{i, err})
+			continue
+		}
+		if ok {
+			postedByKey[entry.IdempotencyKey] = posted
+			batch.IDs[i] = posted.ID
+			continue
+		}
+
+		entryIDAndInventory, err := copyEntryInventories(entry, batch.IDAndInventory, dbCommand)
+		if err != nil {
+			return checkedBatch{}, err
+		}
+
+		entry, entryIDAndInventory, err = processEntryAfter(entry, entryIDAndInventory, lastEntry, lastID, lastHash, requiredDimensions)
+		if err != nil {
+			batch.issues = append(batch.issues, BatchIssue{i, err})
+			continue
+		}
+		maps.Copy(batch.IDAndInventory, entryIDAndInventory)
+		lastID, lastHash = entry.ID, entry.Hash
+		lastEntry = entry
+
+		if entry.IdempotencyKey != "" {
+			postedByKey[entry.IdempotencyKey] = entry
+		}
+		batch.IDs[i] = entry.ID
+		batch.newEntries = append(batch.newEntries, entry)
+	}
+	return batch, nil
+}
+
+// copyEntryInventories returns a copy of the inventories of the accounts of the entry, so an invalid entry
+// leaves IDAndInventory as it is. An account that is not in IDAndInventory is read from dbCommand and added to it,
+// or left out if dbCommand is nil.
+func copyEntryInventories(entry AccountingEntry, IDAndInventory AccountIDAndInventory, dbCommand DB) (AccountIDAndInventory, error) {
+	entryIDAndInventory := make(AccountIDAndInventory)
+	for _, single := range entry.DoubleEntry {
+		inv, ok := IDAndInventory[single.AccountID]
+		if !ok && dbCommand == nil {
+			continue
+		}
+		if !ok {
+			var err error
+			inv, err = dbCommand.GetInventory(single.AccountID)
+			if err != nil {
+				return nil, err
+			}
+			IDAndInventory[single.AccountID] = inv
+		}
+		entryIDAndInventory[single.AccountID] = slices.Clone(inv)
+	}
+	return entryIDAndInventory, nil
+}
@@ -0,0 +1,119 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+)
+
+func Test_AddBatch(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	_, err := AddToJournal(AccountingEntry{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}, IdempotencyKey: "capital"}, &kk)
+	fTest(err, nil)
+
+	IDs, issues, err := AddBatch([]AccountingEntry{
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{FIFO, 1001, 20, 200}, {INFLOW, 3001, 20, 200}}},
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+		{TimeUnix: 3, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, 3001, 5, 50}}, IdempotencyKey: "sale"},
+		{TimeUnix: 3, DoubleEntry: DoubleEntry{{FIFO, 1001, 6, 60}, {INFLOW, 3001, 6, 60}}, IdempotencyKey: "sale"},
+	}, &kk)
+	fTest(IDs, nil)
+	fTest(len(issues), 3)
+	fTest(issues[0].Index, 1)
+	fTest(goerrors.NormalizeTheError(issues[0].Err), fmt.Errorf("ErrInsufficientQuantityInInventory : You want to withdraw quantity = 20 but you do not have enough quantity because your total quantity = 10"))
+	fTest(issues[1].Index, 2)
+	fTest(goerrors.NormalizeTheError(issues[1].Err), fmt.Errorf("ErrTimeShouldBeBigger : time should be bigger"))
+	fTest(issues[2].Index, 4)
+	fTest(goerrors.NormalizeTheError(issues[2].Err), fmt.Errorf(`ErrIdempotencyKeyReused : the idempotency key "sale" was used for the entry with ID 3 that has a different content`))
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrBatchHasInvalidEntries : 3 of the 5 entries of the batch are invalid"))
+
+	// nothing is posted when an entry is invalid
+	fTest(len(kk.myEntries), 1)
+	fTest(kk.myInv, AccountIDAndInventory{-1001: Inventory{{1, 0, 1000}}, 2001: Inventory{{1, 1000, 1000}}})
+
+	IDs, issues, err = AddBatch([]AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}, IdempotencyKey: "capital"},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 200}, {WAC, 2001, 200, 200}}},
+		{TimeUnix: 3, DoubleEntry: DoubleEntry{{FIFO, 1001, 15, 200}, {INFLOW, 3001, 15, 200}}, IdempotencyKey: "sale"},
+		{TimeUnix: 3, DoubleEntry: DoubleEntry{{FIFO, 1001, 15, 200}, {INFLOW, 3001, 15, 200}}, IdempotencyKey: "sale"},
+	}, &kk)
+	fTest(err, nil)
+	fTest(issues, nil)
+	fTest(IDs, []EntryID{1, 2, 3, 4, 4})
+	fTest(len(kk.myEntries), 4)
+	fTest(kk.myEntries[2].Sequence, 1)
+	fTest(kk.lastEntry.ID, 4)
+	fTest(kk.myInv, AccountIDAndInventory{
+		-1001: Inventory{{1, 0, 1000}},
+		1001:  Inventory{{2, 5, 100}},
+		2001:  Inventory{{2, 700, 700}},
+		3001:  Inventory{{3, 15, 200}},
+	})
+
+	myInvExpected := kk.myInv
+	kk.myInv = make(AccountIDAndInventory)
+	fTest(CheckAllTheJournal(&kk), nil)
+	fTest(kk.myInv, myInvExpected)
+}
+
+// myBatchDB is myDB that stores a batch in one write, or fails without writing anything.
+type myBatchDB struct {
+	*myDB
+	err error
+}
+
+func (s myBatchDB) SetEntry(AccountingEntry) error {
+	return fmt.Errorf("a batch should be stored with SetEntriesAndInventories")
+}
+
+func (s myBatchDB) SetEntriesAndInventories(entries []AccountingEntry, IDAndInventory AccountIDAndInventory) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, entry := range entries {
+		err := s.myDB.SetEntry(entry)
+		if err != nil {
+			return err
+		}
+	}
+	for ID, inv := range IDAndInventory {
+		err := s.myDB.SetInventory(ID, inv)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_AddBatch_BatchDB(t *testing.T) {
+	kk := myBatchDB{myDB: &myDB{myInv: make(AccountIDAndInventory)}}
+	batch := []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+	}
+
+	kk.err = fmt.Errorf("the transaction is rolled back")
+	IDs, issues, err := AddBatch(batch, kk)
+	fTest(err, kk.err)
+	fTest(issues, nil)
+	fTest(IDs, nil)
+	fTest(len(kk.myEntries), 0)
+	fTest(kk.myInv, AccountIDAndInventory{})
+
+	kk.err = nil
+	IDs, issues, err = AddBatch(batch, kk)
+	fTest(err, nil)
+	fTest(issues, nil)
+	fTest(IDs, []EntryID{1, 2})
+	fTest(len(kk.myEntries), 2)
+	fTest(kk.myInv, AccountIDAndInventory{
+		-1001: Inventory{{1, 0, 1000}},
+		1001:  Inventory{{2, 10, 100}},
+		2001:  Inventory{{2, 900, 900}},
+	})
+}
@@ -0,0 +1,659 @@
+package accounting
+
+import (
+	"bufio"
+	"cmp"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// beancountBookings are the cost flow types of the booking methods of the open directives of Beancount.
+var beancountBookings = map[string]CostFlowType{
+	"FIFO":    FIFO,
+	"LIFO":    LIFO,
+	"HIFO":    HIFO,
+	"AVERAGE": WAC,
+}
+
+// WriteBeancount writes the whole journal in the plain-text format of Beancount with the cost basis of every lot.
+//
+// The accounts of the config are opened at the date of the first entry, the accounts with a commodity
+// other than the Currency with the "FIFO" booking method. Every entry is a transaction with its Description
+// as its narration, its Reference as its link and its Tags, and every line is a posting of its account with
+// a positive number for a debit and a negative number for a credit.
+//
+// A line of a quantity of a commodity is written as one posting for every cost layer that it adds to or
+// takes out of the inventory, with the cost of the layer, its acquisition date and its time as the label
+// of the lot if it is not the start of a day in UTC, so Beancount reduces exactly the same lots:
+//
+//	2024-03-03 * "sale" ^INV-1
+//	  time: "2024-03-03T10:00:00Z"
+//	  Assets:Inventory  -4 WIDGET {10 USD, 2024-03-01}
+//	  Assets:Inventory  -2 WIDGET {12 USD, 2024-03-02}
+//	    cost_flow: "LIFO"
+//	  Expenses:Cost  64 USD
+//
+// The time is written as the time metadata of the transaction if it is not the start of a day in UTC,
+// a Reference that is not a valid link as its reference metadata, and the cost flow type as the cost_flow
+// metadata of the posting if it is not the one that ReadBeancount gives to a posting without it.
+// A WAC line is written as one lot without a date, Beancount books it only in an account with the "AVERAGE" booking method.
+func WriteBeancount(w io.Writer, dbCommand DB, config PlainTextConfig) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString("option \"operating_currency\" " + strconv.Quote(config.Currency) + "\n")
+
+	var lastEntry AccountingEntry
+	isFirst := true
+	IDAndInventory := make(AccountIDAndInventory)
+	for entry, err := range dbCommand.Journal(JournalRange{}) {
+		if err != nil {
+			return err
+		}
+
+		if isFirst {
+			writeBeancountOpens(writer, entry.TimeUnix, config)
+		}
+		isFirst = false
+
+		writer.WriteString("\n")
+		err = writeBeancountTransaction(writer, entry, IDAndInventory, config)
+		if err != nil {
+			return err
+		}
+
+		IDAndInventory, err = CheckAndProcessDoubleEntry(lastEntry.TimeUnix, entry, IDAndInventory)
+		if err != nil {
+			return err
+		}
+
+		lastEntry = entry
+	}
+	return writer.Flush()
+}
+
+func writeBeancountOpens(writer *bufio.Writer, timeUnix TimeUnix, config PlainTextConfig) {
+	names := make([]string, 0, len(config.Accounts))
+	bookings := make(map[string]bool, len(config.Accounts))
+	for ID, name := range config.Accounts {
+		names = append(names, name)
+		commodity := config.Commodities[ID]
+		bookings[name] = commodity != "" && commodity != config.Currency
+	}
+	slices.Sort(names)
+
+	writer.WriteString("\n")
+	date := time.UnixMicro(timeUnix).UTC().Format(time.DateOnly)
+	for _, name := range names {
+		line := date + " open " + name
+		if bookings[name] {
+			line += " \"FIFO\""
+		}
+		writer.WriteString(line + "\n")
+	}
+}
+
+// beancountSymbol is the name of a tag or of a link.
+var beancountSymbol = regexp.MustCompile(`^[A-Za-z0-9\-_/.]+$`)
+
+// writeBeancountTransaction writes the entry, the inventories are the inventories just before it.
+func writeBeancountTransaction(writer *bufio.Writer, entry AccountingEntry, IDAndInventory AccountIDAndInventory, config PlainTextConfig) error {
+	t := time.UnixMicro(entry.TimeUnix).UTC()
+	header := t.Format(time.DateOnly) + " * " + strconv.Quote(entry.Description)
+	isLink := beancountSymbol.MatchString(entry.Reference)
+	if isLink {
+		header += " ^" + entry.Reference
+	}
+	for _, tag := range entry.Tags {
+		if !beancountSymbol.MatchString(tag) {
+			return newError(ErrPlainTextMapping, "the tag %q is not a valid tag of Beancount", tag)
+		}
+		header += " #" + tag
+	}
+	writer.WriteString(header + "\n")
+
+	if !t.Equal(t.Truncate(24 * time.Hour)) {
+		writer.WriteString("  time: " + strconv.Quote(t.Format(time.RFC3339Nano)) + "\n")
+	}
+	if entry.Reference != "" && !isLink {
+		writer.WriteString("  reference: " + strconv.Quote(entry.Reference) + "\n")
+	}
+
+	for _, single := range entry.DoubleEntry {
+		p, err := toTextPosting(single, config)
+		if err != nil {
+			return err
+		}
+
+		if p.commodity == "" {
+			writer.WriteString("  " + p.account + "  " + formatTextNumber(p.amount) + " " + config.Currency + "\n")
+		} else {
+			layers := movedLayers(entry, single, IDAndInventory[single.AccountID])
+			isDated := layers != nil
+			if !isDated {
+				layers = Inventory{{0, single.Quantity, single.Amount}}
+			}
+			for _, layer := range layers {
+				quantity := math.Copysign(float64(layer.Quantity), float64(p.quantity))
+				writer.WriteString("  " + p.account + "  " + formatTextNumber(quantity) + " " + p.commodity + " " +
+					formatBeancountCost(layer, isDated, config.Currency) + "\n")
+			}
+		}
+
+		if p.costFlowType != "" {
+			writer.WriteString("    cost_flow: " + strconv.Quote(p.costFlowType) + "\n")
+		}
+	}
+	return nil
+}
+
+// formatBeancountCost returns the cost of the lot like {10 USD, 2024-03-02} or {# 100 USD} if its price is not exact.
+func formatBeancountCost(layer InventoryRecord, isDated bool, currency string) string {
+	cost := "# " + formatTextNumber(layer.Amount) + " " + currency
+	if price := layer.Amount / Amount(layer.Quantity); price*Amount(layer.Quantity) == layer.Amount {
+		cost = formatTextNumber(price) + " " + currency
+	}
+
+	if isDated {
+		t := time.UnixMicro(layer.TimeUnix).UTC()
+		cost += ", " + t.Format(time.DateOnly)
+		if !t.Equal(t.Truncate(24 * time.Hour)) {
+			cost += ", " + strconv.Quote(t.Format(time.RFC3339Nano))
+		}
+	}
+	return "{" + cost + "}"
+}
+
+// beancountPosting is a posting that ReadBeancount is reading.
+type beancountPosting struct {
+	textPosting
+	isCostKnown bool     // false if the booking method of the account gives the amount of the quantity like for {}
+	price       Amount   // the total price after @ or @@, NaN if the posting has no price
+	isDated     bool     // true if the cost has the acquisition date of the lot
+	lotTime     TimeUnix // the acquisition time of the lot from its date and its label
+}
+
+// beancountTransaction is a transaction that ReadBeancount is reading.
+type beancountTransaction struct {
+	AccountingEntry
+	postings []beancountPosting
+}
+
+// ReadBeancount reads the transactions of a Beancount file as entries in the order of their dates,
+// the file should have the whole history of the accounts because the postings refer to the lots of the transactions before them.
+//
+// The narration of a transaction is the Description of its entry with its payee before it like "payee: narration",
+// its first link is its Reference and its tags are its Tags. The entry is at the start of the day of the transaction
+// in UTC, or at the time in its time metadata. A reference metadata is the Reference too.
+//
+// A posting of an amount of the Currency is a line of the amount, its quantity is its amount if the Currency
+// is the commodity of its account. A posting of a quantity of another commodity is a lot with its cost in
+// the Currency like {10 USD}, {# 100 USD}, {{100 USD}} or {10 # 5 USD}. A lot that is added without a cost
+// gets the price after @ or @@ as its cost, and a lot that is taken out without a cost like {} gets the cost that
+// the booking method of its account takes out of the inventory. A posting without an amount gets the amount
+// that balances the transaction.
+//
+// The postings of the lots of an account in a transaction are one line. The lots that have an acquisition date
+// like {10 USD, 2024-03-02} are the Layers of the line, if they are not the layers that its cost flow type moves,
+// then a line that takes them out is NONE so it takes out exactly these lots. A lot with a date and without a cost
+// like {2024-03-02} takes out the lots of the date by the booking method of its account.
+// The label of a lot like "2024-03-02T10:00:00Z" is its time in the day of the date.
+//
+// The cost flow type of a posting is the name in its cost_flow metadata, or INFLOW if it increases its account,
+// NONE if it decreases its quantity or its amount only and otherwise the cost flow type of the booking method
+// of the open directive of its account: FIFO, LIFO, HIFO or WAC for "AVERAGE", and FIFO for the other methods.
+//
+// The other directives are skipped. The entries are checked by processing them from empty inventories.
+//
+// Returns the entries, or an Error with the name ErrInvalidPlainText or ErrPlainTextMapping for the first invalid line,
+// or the error of the first entry that can't be processed.
+func ReadBeancount(r io.Reader, config PlainTextConfig) ([]AccountingEntry, error) {
+	accountIDs := reverseAccounts(config)
+	bookings := make(map[string]CostFlowType)
+
+	var transactions []*beancountTransaction
+	var current *beancountTransaction
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			current = nil
+
+		case trimmed[0] == ';':
+			continue
+
+		case line[0] == ' ' || line[0] == '\t':
+			if current == nil { // a line of a skipped directive
+				continue
+			}
+			err := readBeancountTransactionLine(lineNumber, trimmed, current, config)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			current = nil
+			fields := strings.Fields(trimmed)
+			if len(fields) < 3 || !beancountDate.MatchString(fields[0]) { // like option or include
+				continue
+			}
+			switch fields[1] {
+			case "*", "!", "txn":
+				var err error
+				current, err = readBeancountHeader(lineNumber, trimmed)
+				if err != nil {
+					return nil, err
+				}
+				transactions = append(transactions, current)
+			case "open":
+				if costFlowType, ok := beancountBookings[strings.Trim(fields[len(fields)-1], `"`)]; ok {
+					bookings[fields[2]] = costFlowType
+				}
+			}
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(transactions, func(a, b *beancountTransaction) int {
+		return cmp.Compare(a.TimeUnix, b.TimeUnix)
+	})
+
+	var entries []AccountingEntry
+	var lastTimeUnix TimeUnix
+	IDAndInventory := make(AccountIDAndInventory)
+	for _, transaction := range transactions {
+		entry, err := bookBeancountTransaction(transaction, IDAndInventory, bookings, config, accountIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		IDAndInventory, err = CheckAndProcessDoubleEntry(lastTimeUnix, entry, IDAndInventory)
+		if err != nil {
+			return nil, err
+		}
+
+		lastTimeUnix = entry.TimeUnix
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+var beancountDate = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+
+// readBeancountHeader reads DATE FLAG ["PAYEE"] ["NARRATION"] [#TAG ...] [^LINK ...].
+func readBeancountHeader(lineNumber int, line string) (*beancountTransaction, error) {
+	date, rest, _ := strings.Cut(line, " ")
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return nil, fErrInvalidPlainText(lineNumber, "its date "+date+" is not a date")
+	}
+
+	current := &beancountTransaction{}
+	current.TimeUnix = t.UnixMicro()
+
+	var texts []string
+	_, fields, _ := strings.Cut(strings.TrimSpace(rest), " ") // after the flag
+	for fields = strings.TrimSpace(fields); fields != "" && fields[0] != ';'; fields = strings.TrimSpace(fields) {
+		if fields[0] == '"' {
+			text, err := strconv.QuotedPrefix(fields)
+			if err != nil {
+				return nil, fErrInvalidPlainText(lineNumber, "its string "+fields+" has no end")
+			}
+			fields = fields[len(text):]
+			text, _ = strconv.Unquote(text)
+			texts = append(texts, text)
+			continue
+		}
+
+		symbol, after, _ := strings.Cut(fields, " ")
+		fields = after
+		switch {
+		case len(symbol) > 1 && symbol[0] == '#':
+			current.Tags = append(current.Tags, symbol[1:])
+		case len(symbol) > 1 && symbol[0] == '^':
+			if current.Reference == "" {
+				current.Reference = symbol[1:]
+			}
+		default:
+			return nil, fErrInvalidPlainText(lineNumber, "its "+symbol+" is not a string, a tag or a link")
+		}
+	}
+
+	switch len(texts) {
+	case 0:
+	case 1:
+		current.Description = texts[0]
+	case 2:
+		current.Description = texts[1]
+		if texts[0] != "" {
+			current.Description = texts[0] + ": " + texts[1]
+		}
+	default:
+		return nil, fErrInvalidPlainText(lineNumber, "it has more than a payee and a narration")
+	}
+	return current, nil
+}
+
+// beancountMetadata is KEY: VALUE, the keys start with a lowercase letter and the accounts with an uppercase letter.
+var beancountMetadata = regexp.MustCompile(`^([a-z][A-Za-z0-9_-]*):\s*(.*)$`)
+
+// readBeancountTransactionLine reads a posting, a metadata or a comment of the transaction,
+// a metadata after a posting is the metadata of the posting.
+func readBeancountTransactionLine(lineNumber int, line string, current *beancountTransaction, config PlainTextConfig) error {
+	if line[0] == ';' {
+		return nil
+	}
+
+	if match := beancountMetadata.FindStringSubmatch(line); match != nil {
+		value := strings.TrimSpace(match[2])
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+
+		if len(current.postings) != 0 {
+			if match[1] == "cost_flow" {
+				current.postings[len(current.postings)-1].costFlowType = value
+			}
+			return nil
+		}
+
+		switch match[1] {
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return fErrInvalidPlainText(lineNumber, "its time "+value+" is not RFC 3339")
+			}
+			current.TimeUnix = t.UnixMicro()
+		case "reference":
+			current.Reference = value
+		}
+		return nil
+	}
+
+	line, _, _ = strings.Cut(line, ";")
+	if line[0] == '*' || line[0] == '!' {
+		line = strings.TrimSpace(line[1:])
+	}
+	account, expression, _ := strings.Cut(line, " ")
+	p := beancountPosting{textPosting: textPosting{line: lineNumber, account: account}, price: Amount(math.NaN())}
+	err := readBeancountAmount(lineNumber, strings.TrimSpace(expression), &p, config)
+	if err != nil {
+		return err
+	}
+	current.postings = append(current.postings, p)
+	return nil
+}
+
+// beancountAmount is a number with its commodity after it like -5 WIDGET.
+var beancountAmount = regexp.MustCompile(`^(-?[0-9][0-9,]*(?:\.[0-9]*)?)\s+([A-Z][A-Z0-9'._-]*)$`)
+
+// readBeancountAmount reads the amount of the posting with its cost and its price,
+// an empty amount is the amount that balances the transaction.
+func readBeancountAmount(lineNumber int, expression string, p *beancountPosting, config PlainTextConfig) error {
+	if expression == "" {
+		p.amount = Amount(math.NaN())
+		return nil
+	}
+
+	var price string
+	isTotalPrice := false
+	if before, after, ok := strings.Cut(expression, "@@"); ok {
+		expression, price, isTotalPrice = before, after, true
+	} else if before, after, ok := strings.Cut(expression, "@"); ok {
+		expression, price = before, after
+	}
+
+	var cost string
+	isTotalCost := false
+	if i := strings.Index(expression, "{"); i >= 0 {
+		cost = expression[i:]
+		expression = expression[:i]
+		end := strings.LastIndex(cost, "}")
+		if end < 0 {
+			return fErrInvalidPlainText(lineNumber, "its cost has no }")
+		}
+		isTotalCost = strings.HasPrefix(cost, "{{")
+		cost = strings.Trim(cost[:end], "{} ")
+	}
+
+	quantity, commodity, err := parseBeancountAmount(lineNumber, expression)
+	if err != nil {
+		return err
+	}
+	if commodity == config.Currency {
+		p.amount = Amount(quantity)
+		return nil
+	}
+	p.quantity = Quantity(quantity)
+	p.commodity = commodity
+
+	if strings.TrimSpace(price) != "" {
+		priceValue, err := parseBeancountCurrency(lineNumber, price, config)
+		if err != nil {
+			return err
+		}
+		if !isTotalPrice {
+			priceValue *= quantity
+		}
+		p.price = Amount(math.Copysign(math.Abs(priceValue), quantity))
+	}
+
+	return readBeancountCost(lineNumber, cost, isTotalCost, p, config)
+}
+
+// readBeancountCost reads the cost of the lot like 10 USD, 2024-03-02, "label".
+func readBeancountCost(lineNumber int, cost string, isTotal bool, p *beancountPosting, config PlainTextConfig) error {
+	var date time.Time
+	var label string
+	for cost = strings.TrimSpace(cost); cost != ""; cost = strings.TrimSpace(cost) {
+		var component string
+		if cost[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(cost)
+			if err != nil {
+				return fErrInvalidPlainText(lineNumber, "its label "+cost+" has no end")
+			}
+			label, _ = strconv.Unquote(quoted)
+			cost = strings.TrimPrefix(strings.TrimSpace(cost[len(quoted):]), ",")
+			continue
+		}
+		component, cost, _ = strings.Cut(cost, ",")
+		component = strings.TrimSpace(component)
+
+		switch {
+		case beancountDate.MatchString(component):
+			var err error
+			date, err = time.Parse(time.DateOnly, component)
+			if err != nil {
+				return fErrInvalidPlainText(lineNumber, "its date "+component+" is not a date")
+			}
+			p.isDated = true
+		case component == "*":
+			return fErrInvalidPlainText(lineNumber, "the average cost {*} is not supported")
+		default:
+			perUnit, total, hasTotal := strings.Cut(component, "#")
+			if !hasTotal {
+				perUnit, total = "", component
+				if !isTotal {
+					perUnit, total = component, ""
+				}
+			}
+
+			var value float64
+			if perUnit = strings.TrimSpace(perUnit); perUnit != "" {
+				if !strings.ContainsAny(perUnit, " \t") { // like 10 # 5 USD
+					perUnit += " " + config.Currency
+				}
+				perUnitValue, err := parseBeancountCurrency(lineNumber, perUnit, config)
+				if err != nil {
+					return err
+				}
+				value += math.Abs(perUnitValue * float64(p.quantity))
+			}
+			if total = strings.TrimSpace(total); total != "" {
+				totalValue, err := parseBeancountCurrency(lineNumber, total, config)
+				if err != nil {
+					return err
+				}
+				value += math.Abs(totalValue)
+			}
+			p.amount = Amount(math.Copysign(value, float64(p.quantity)))
+			p.isCostKnown = true
+		}
+	}
+
+	p.lotTime = date.UnixMicro()
+	if t, err := time.Parse(time.RFC3339Nano, label); err == nil && p.isDated && t.UTC().Truncate(24*time.Hour).Equal(date) {
+		p.lotTime = t.UnixMicro()
+	}
+	return nil
+}
+
+// parseBeancountCurrency parses an amount of the Currency like 10 USD.
+func parseBeancountCurrency(lineNumber int, expression string, config PlainTextConfig) (float64, error) {
+	value, commodity, err := parseBeancountAmount(lineNumber, expression)
+	if err != nil {
+		return 0, err
+	}
+	if commodity != config.Currency {
+		return 0, fErrInvalidPlainText(lineNumber, "its cost is in "+commodity+" instead of "+config.Currency)
+	}
+	return value, nil
+}
+
+func parseBeancountAmount(lineNumber int, expression string) (float64, string, error) {
+	expression = strings.TrimSpace(expression)
+	match := beancountAmount.FindStringSubmatch(expression)
+	if match == nil {
+		return 0, "", fErrInvalidPlainText(lineNumber, "its amount "+expression+" is not a number with a commodity")
+	}
+
+	value, err := parseTextNumber(match[1])
+	if err != nil {
+		return 0, "", fErrInvalidPlainText(lineNumber, "its amount "+expression+" is not a number with a commodity")
+	}
+	return value, match[2], nil
+}
+
+// bookBeancountTransaction returns the entry of the transaction, the inventories are the inventories just before it.
+func bookBeancountTransaction(transaction *beancountTransaction, IDAndInventory AccountIDAndInventory, bookings map[string]CostFlowType, config PlainTextConfig, accountIDs map[string]AccountID) (AccountingEntry, error) {
+	// the postings of the lots of an account are one line with the lots as its layers
+	var postings []textPosting
+	indexes := make(map[AccountID]int)
+	isDated := make(map[AccountID]bool)
+	lots := make(AccountIDAndInventory)
+	for _, p := range transaction.postings {
+		if p.commodity == "" {
+			postings = append(postings, p.textPosting)
+			continue
+		}
+
+		ID, ok := accountIDs[p.account]
+		if !ok {
+			return AccountingEntry{}, newError(ErrPlainTextMapping, "the account %v on line %v has no account ID", p.account, p.line)
+		}
+
+		isLotTaken := false
+		if !p.isCostKnown {
+			isIncrease := (p.quantity > 0) == bool(IsNatureDebit(ID))
+			switch {
+			case isIncrease && math.IsNaN(float64(p.price)):
+				return AccountingEntry{}, fErrInvalidPlainText(p.line, "the quantity of "+p.commodity+" has no cost in "+config.Currency)
+			case isIncrease:
+				p.amount = p.price
+			default:
+				costFlowType, err := beancountCostFlowType(p.textPosting, bookings)
+				if err != nil {
+					return AccountingEntry{}, err
+				}
+				qty := Quantity(math.Abs(float64(p.quantity)))
+				if !p.isDated {
+					amount := bookedAmount(costFlowType, qty, IDAndInventory[ID])
+					p.amount = Amount(math.Copysign(float64(amount), float64(p.quantity)))
+					break
+				}
+
+				// the lots of the date are booked by the cost flow type and each of them is taken out exactly
+				inventoryVariable := slices.DeleteFunc(slices.Clone(IDAndInventory[ID]), func(record InventoryRecord) bool {
+					return record.TimeUnix != p.lotTime
+				})
+				sortInventoryByCostFlow(costFlowType, inventoryVariable)
+				taken := takeLayers(qty, inventoryVariable)
+				_, amount := GetTotalInventory(taken)
+				p.amount = Amount(math.Copysign(float64(amount), float64(p.quantity)))
+				lots[ID] = append(lots[ID], taken...)
+				isLotTaken = true
+			}
+		}
+
+		if p.isDated && !isLotTaken {
+			lots[ID] = append(lots[ID], InventoryRecord{p.lotTime, Quantity(math.Abs(float64(p.quantity))), Amount(math.Abs(float64(p.amount)))})
+		}
+
+		i, ok := indexes[ID]
+		if !ok {
+			indexes[ID] = len(postings)
+			isDated[ID] = p.isDated
+			postings = append(postings, p.textPosting)
+			continue
+		}
+
+		switch {
+		case (postings[i].quantity > 0) != (p.quantity > 0):
+			return AccountingEntry{}, fErrInvalidPlainText(p.line, "its lot is not in the direction of the lots of "+p.account+" before it")
+		case isDated[ID] != p.isDated:
+			return AccountingEntry{}, fErrInvalidPlainText(p.line, "the lots of "+p.account+" should all have a date or none of them")
+		}
+		postings[i].quantity += p.quantity
+		postings[i].amount += p.amount
+		if p.costFlowType != "" {
+			postings[i].costFlowType = p.costFlowType
+		}
+	}
+
+	entry, err := finishTextTransaction(transaction.AccountingEntry, postings, config, accountIDs)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+
+	for i, single := range entry.DoubleEntry {
+		if postings[i].costFlowType == "" && single.CostFlowType == FIFO {
+			entry.DoubleEntry[i].CostFlowType, _ = beancountCostFlowType(postings[i], bookings)
+		}
+	}
+
+	keepLots(&entry, indexes, lots, IDAndInventory)
+	return entry, nil
+}
+
+// beancountCostFlowType returns the cost flow type of a posting that takes out of its account.
+func beancountCostFlowType(p textPosting, bookings map[string]CostFlowType) (CostFlowType, error) {
+	if p.costFlowType != "" {
+		return ParseCostFlowType(p.costFlowType)
+	}
+	if costFlowType, ok := bookings[p.account]; ok {
+		return costFlowType, nil
+	}
+	return FIFO, nil
+}
+
+// bookedAmount returns the amount that the cost flow type takes out of the inventory for the quantity.
+func bookedAmount(costFlowType CostFlowType, qty Quantity, inventoryVariable Inventory) Amount {
+	inventoryVariable = slices.Clone(inventoryVariable)
+	if costFlowType == WAC {
+		totalQuantity, totalAmount := GetTotalInventory(inventoryVariable)
+		inventoryVariable = Inventory{{0, totalQuantity, totalAmount}}
+	}
+	sortInventoryByCostFlow(costFlowType, inventoryVariable)
+
+	_, amount := GetTotalInventory(takeLayers(qty, inventoryVariable))
+	return amount
+}
@@ -0,0 +1,281 @@
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+var beancountConfig = PlainTextConfig{
+	Currency: "USD",
+	Accounts: map[AccountID]string{
+		-1001: "Equity:Capital",
+		-4001: "Income:Sales",
+		1001:  "Assets:Inventory:Widgets",
+		2001:  "Assets:Cash",
+		3001:  "Expenses:CostOfGoods",
+	},
+	Commodities: plainTextConfig.Commodities,
+}
+
+func Test_WriteBeancount(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+	entries := append(slices.Clone(plainTextEntries),
+		AccountingEntry{TimeUnix: 1709510400000000, DoubleEntry: DoubleEntry{{INFLOW, 1001, 5, 60}, {WAC, 2001, 60, 60}}, Metadata: Metadata{Reference: "PO 2", Description: "purchase"}},
+		AccountingEntry{TimeUnix: 1709596800000000, DoubleEntry: DoubleEntry{{FIFO, 1001, 8, 84}, {INFLOW, 3001, 8, 84}, {INFLOW, 2001, 120, 120}, {INFLOW, -4001, 0, 120}}, Metadata: Metadata{Description: "sale"}},
+	)
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	var b bytes.Buffer
+	fTest(WriteBeancount(&b, &kk, beancountConfig), nil)
+	fTest(b.String(), `option "operating_currency" "USD"
+
+2024-03-01 open Assets:Cash
+2024-03-01 open Assets:Inventory:Widgets "FIFO"
+2024-03-01 open Equity:Capital
+2024-03-01 open Expenses:CostOfGoods "FIFO"
+2024-03-01 open Income:Sales
+
+2024-03-01 * "capital"
+  Equity:Capital  -1000 USD
+  Assets:Cash  1000 USD
+
+2024-03-02 * "purchase" ^PO-1 #supplier #q1
+  time: "2024-03-02T10:00:00Z"
+  Assets:Inventory:Widgets  10 WIDGET {10 USD, 2024-03-02, "2024-03-02T10:00:00Z"}
+  Assets:Cash  -100 USD
+    cost_flow: "WAC"
+
+2024-03-03 * "sale" ^INV-1
+  time: "2024-03-03T10:00:00Z"
+  Assets:Inventory:Widgets  -4 WIDGET {10 USD, 2024-03-02, "2024-03-02T10:00:00Z"}
+    cost_flow: "LIFO"
+  Expenses:CostOfGoods  4 WIDGET {10 USD, 2024-03-03, "2024-03-03T10:00:00Z"}
+  Assets:Cash  60 USD
+  Income:Sales  -60 USD
+
+2024-03-04 * "purchase"
+  reference: "PO 2"
+  Assets:Inventory:Widgets  5 WIDGET {12 USD, 2024-03-04}
+  Assets:Cash  -60 USD
+    cost_flow: "WAC"
+
+2024-03-05 * "sale"
+  Assets:Inventory:Widgets  -6 WIDGET {10 USD, 2024-03-02, "2024-03-02T10:00:00Z"}
+  Assets:Inventory:Widgets  -2 WIDGET {12 USD, 2024-03-04}
+  Expenses:CostOfGoods  8 WIDGET {10.5 USD, 2024-03-05}
+  Assets:Cash  120 USD
+  Income:Sales  -120 USD
+`)
+
+	// the written file is read as the same entries
+	imported, err := ReadBeancount(&b, beancountConfig)
+	fTest(err, nil)
+	fTest(imported, entries)
+}
+
+func Test_ReadBeancount(t *testing.T) {
+	type input struct {
+		file string
+	}
+	type output struct {
+		AccountingEntries []AccountingEntry
+		err               error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `option "operating_currency" "USD"
+plugin "beancount.plugins.auto_accounts"
+
+2024-03-01 open Assets:Inventory:Widgets WIDGET "LIFO"
+2024-03-01 open Assets:Cash USD
+
+; the sale is before the purchases in the file
+2024-03-05 * "Shop" "sale" #retail
+  Assets:Inventory:Widgets  -6 WIDGET {} ; at the cost of the last lots
+  Expenses:CostOfGoods
+  Assets:Cash  90 USD
+  Income:Sales  -90 USD
+
+2024-03-02 txn "purchase"
+  Assets:Inventory:Widgets  10 WIDGET {10 USD}
+  Equity:Capital  -1,100.00 USD
+  Assets:Cash  1,000.00 USD
+
+2024-03-03 ! "purchase" ^PO-2
+  reference: "PO 2"
+  Assets:Inventory:Widgets  5 WIDGET @ 12 USD
+  Assets:Cash
+    cost_flow: "WAC"
+
+2024-03-04 balance Assets:Cash  940 USD
+
+2024-03-04 * "return" ^R-1
+  time: "2024-03-04T09:30:00Z"
+  Assets:Inventory:Widgets  -3 WIDGET {10 USD, 2024-03-02}
+  Equity:Capital  30 USD
+`,
+			},
+			output: output{
+				AccountingEntries: []AccountingEntry{
+					{
+						TimeUnix:    1709337600000000,
+						DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 0, 1100}, {INFLOW, 2001, 1000, 1000}},
+						Metadata:    Metadata{Description: "purchase"},
+					},
+					{
+						TimeUnix:    1709424000000000,
+						DoubleEntry: DoubleEntry{{INFLOW, 1001, 5, 60}, {WAC, 2001, 60, 60}},
+						Metadata:    Metadata{Reference: "PO 2", Description: "purchase"},
+					},
+					{
+						TimeUnix:    1709544600000000,
+						DoubleEntry: DoubleEntry{{NONE, 1001, 3, 30}, {NONE, -1001, 0, 30}},
+						Metadata:    Metadata{Reference: "R-1", Description: "return"},
+						Layers:      AccountIDAndInventory{1001: {{1709337600000000, 3, 30}}},
+					},
+					{
+						TimeUnix:    1709596800000000,
+						DoubleEntry: DoubleEntry{{LIFO, 1001, 6, 70}, {INFLOW, 3001, 0, 70}, {INFLOW, 2001, 90, 90}, {INFLOW, -4001, 0, 90}},
+						Metadata:    Metadata{Description: "Shop: sale", Tags: []string{"retail"}},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `2024-03-01 open Assets:Inventory:Widgets WIDGET "FIFO"
+
+2024-03-02 * "purchase"
+  Assets:Inventory:Widgets  5 WIDGET {20 USD, 2024-03-01}
+  Assets:Inventory:Widgets  5 WIDGET {10 USD, 2024-03-02}
+  Assets:Inventory:Widgets  5 WIDGET {30 USD, 2024-03-02}
+  Equity:Capital
+
+; the lots of the date are taken by FIFO
+2024-03-03 * "sale"
+  Assets:Inventory:Widgets  -6 WIDGET {2024-03-02}
+  Expenses:CostOfGoods
+`,
+			},
+			output: output{
+				AccountingEntries: []AccountingEntry{
+					{
+						TimeUnix:    1709337600000000,
+						DoubleEntry: DoubleEntry{{INFLOW, 1001, 15, 300}, {INFLOW, -1001, 0, 300}},
+						Metadata:    Metadata{Description: "purchase"},
+						Layers:      AccountIDAndInventory{1001: {{1709251200000000, 5, 100}, {1709337600000000, 5, 50}, {1709337600000000, 5, 150}}},
+					},
+					{
+						TimeUnix:    1709424000000000,
+						DoubleEntry: DoubleEntry{{NONE, 1001, 6, 80}, {INFLOW, 3001, 0, 80}},
+						Metadata:    Metadata{Description: "sale"},
+						Layers:      AccountIDAndInventory{1001: {{1709337600000000, 5, 50}, {1709337600000000, 1, 30}}},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `2024-03-01 * "purchase"
+  Assets:Inventory:Widgets  10 WIDGET {}
+  Assets:Cash
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 2 is invalid because the quantity of WIDGET has no cost in USD"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `2024-03-01 * "purchase"
+  Assets:Inventory:Widgets  10 WIDGET {10 USD, 2024-03-01}
+  Assets:Inventory:Widgets  -2 WIDGET {10 USD, 2024-03-01}
+  Assets:Cash
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 3 is invalid because its lot is not in the direction of the lots of Assets:Inventory:Widgets before it"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `2024-03-01 * "purchase"
+  Assets:Inventory:Widgets  10 WIDGET {*}
+  Assets:Cash
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 2 is invalid because the average cost {*} is not supported"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `2024-03-01 * "purchase"
+  Assets:Inventory:Widgets  10 WIDGET {10 EUR}
+  Assets:Cash
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 2 is invalid because its cost is in EUR instead of USD"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `2024-03-01 * "purchase" widgets
+  Assets:Cash  10 USD
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 1 is invalid because its widgets is not a string, a tag or a link"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `2024-03-01 * "sale"
+  Assets:Inventory:Widgets  -2 WIDGET {10 USD, 2024-03-01}
+  Assets:Cash
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInventoryNotFoundForAccountID : inventory not found for account ID 1001"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.AccountingEntries, output.err = ReadBeancount(strings.NewReader(tt.input.file), beancountConfig)
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
@@ -0,0 +1,244 @@
+package accounting
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 document that ReadCAMT053 reads.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	IBAN     string        `xml:"Acct>Id>IBAN"`
+	Other    string        `xml:"Acct>Id>Othr>Id"`
+	Currency string        `xml:"Acct>Ccy"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Type        string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	Date        string     `xml:"Dt>Dt"`
+	DateTime    string     `xml:"Dt>DtTm"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus is like <Sts>BOOK</Sts> in the old versions and <Sts><Cd>BOOK</Cd></Sts> in the new ones.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtEntry struct {
+	Reference         string        `xml:"NtryRef"`
+	Amount            camtAmount    `xml:"Amt"`
+	CreditDebit       string        `xml:"CdtDbtInd"`
+	Status            camtStatus    `xml:"Sts"`
+	BookingDate       string        `xml:"BookgDt>Dt"`
+	BookingDateTime   string        `xml:"BookgDt>DtTm"`
+	ServicerReference string        `xml:"AcctSvcrRef"`
+	Details           []camtDetails `xml:"NtryDtls>TxDtls"`
+	Information       string        `xml:"AddtlNtryInf"`
+}
+
+type camtDetails struct {
+	ServicerReference string      `xml:"Refs>AcctSvcrRef"`
+	EndToEndID        string      `xml:"Refs>EndToEndId"`
+	Amount            *camtAmount `xml:"Amt"`
+	CreditDebit       string      `xml:"CdtDbtInd"`
+	Debtor            string      `xml:"RltdPties>Dbtr>Nm"`
+	DebtorParty       string      `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Creditor          string      `xml:"RltdPties>Cdtr>Nm"`
+	CreditorParty     string      `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Remittance        []string    `xml:"RmtInf>Ustrd"`
+}
+
+// ReadCAMT053 reads the bank statement of an ISO 20022 camt.053 file.
+//
+// Every booked Ntry is a transaction with its AcctSvcrRef or its NtryRef as its ID, its BookgDt as its time
+// and its Amt as its amount, negative for DBIT. An Ntry of a batch with the amounts of its TxDtls is one
+// transaction for every TxDtls. The payee is the debtor of money into the account and the creditor of money
+// out of it, and the memo is the unstructured remittance information or the AddtlNtryInf.
+// The IBAN or the other ID of the account is the Account of the statement, its Ccy is its Currency and
+// its last CLBD balance is its ClosingBalance, a balance at a date is at the end of the day.
+// The pending and the information entries are skipped.
+//
+// Returns the statement, or an Error with the name ErrInvalidBankStatement if the file is not a statement of one account
+// in one currency or an entry has no valid amount or date.
+func ReadCAMT053(r io.Reader) (BankStatement, error) {
+	var document camtDocument
+	err := xml.NewDecoder(r).Decode(&document)
+	if err != nil {
+		return BankStatement{}, fErrInvalidBankStatement("it is not XML: " + err.Error())
+	}
+	if len(document.Statements) == 0 {
+		return BankStatement{}, fErrInvalidBankStatement("it has no Stmt element")
+	}
+
+	var statement BankStatement
+	for _, s := range document.Statements {
+		account := strings.TrimSpace(s.IBAN + s.Other)
+		if statement.Account != "" && statement.Account != account {
+			return BankStatement{}, fErrInvalidBankStatement("it has the statements of more than one account")
+		}
+		statement.Account = account
+		if s.Currency != "" {
+			statement.Currency = s.Currency
+		}
+
+		for _, entry := range s.Entries {
+			status := strings.TrimSpace(entry.Status.Code + entry.Status.Text)
+			if status != "" && status != "BOOK" {
+				continue
+			}
+
+			transactions, err := readCAMTEntry(entry, &statement)
+			if err != nil {
+				return BankStatement{}, err
+			}
+			statement.Transactions = append(statement.Transactions, transactions...)
+		}
+
+		for _, balance := range s.Balances {
+			if balance.Type != "CLBD" {
+				continue
+			}
+
+			timeUnix, err := parseCAMTTime("CLBD", balance.Date, balance.DateTime)
+			if err != nil {
+				return BankStatement{}, err
+			}
+			if balance.DateTime == "" {
+				timeUnix = endOfDay(timeUnix)
+			}
+			if timeUnix < statement.ClosingBalance.TimeUnix {
+				continue
+			}
+
+			amount, err := parseCAMTAmount("CLBD", balance.Amount, balance.CreditDebit, &statement)
+			if err != nil {
+				return BankStatement{}, err
+			}
+			statement.ClosingBalance = BankBalance{timeUnix, amount}
+		}
+	}
+	return statement, nil
+}
+
+// readCAMTEntry returns the transactions of the entry, the statement gets the currency of the amounts if it has none.
+func readCAMTEntry(entry camtEntry, statement *BankStatement) ([]BankTransaction, error) {
+	ID := entry.ServicerReference
+	if ID == "" {
+		ID = entry.Reference
+	}
+
+	timeUnix, err := parseCAMTTime(entry.Reference, entry.BookingDate, entry.BookingDateTime)
+	if err != nil {
+		return nil, err
+	}
+
+	isBatch := len(entry.Details) > 1
+	for _, details := range entry.Details {
+		isBatch = isBatch && details.Amount != nil
+	}
+	if !isBatch {
+		transaction := BankTransaction{ID: ID, TimeUnix: timeUnix, Memo: entry.Information}
+		transaction.Amount, err = parseCAMTAmount(ID, entry.Amount, entry.CreditDebit, statement)
+		if err != nil {
+			return nil, err
+		}
+		if len(entry.Details) == 1 {
+			readCAMTDetails(entry.Details[0], &transaction)
+			if transaction.ID == "" {
+				transaction.ID = entry.Details[0].ServicerReference
+			}
+		}
+		return []BankTransaction{transaction}, nil
+	}
+
+	var transactions []BankTransaction
+	for i, details := range entry.Details {
+		transaction := BankTransaction{ID: fmt.Sprintf("%v/%v", ID, i+1), TimeUnix: timeUnix, Memo: entry.Information}
+		switch {
+		case details.ServicerReference != "":
+			transaction.ID = details.ServicerReference
+		case details.EndToEndID != "" && details.EndToEndID != "NOTPROVIDED":
+			transaction.ID = details.EndToEndID
+		}
+
+		creditDebit := details.CreditDebit
+		if creditDebit == "" {
+			creditDebit = entry.CreditDebit
+		}
+		transaction.Amount, err = parseCAMTAmount(transaction.ID, *details.Amount, creditDebit, statement)
+		if err != nil {
+			return nil, err
+		}
+		readCAMTDetails(details, &transaction)
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// readCAMTDetails sets the payee and the memo of the transaction from its details.
+func readCAMTDetails(details camtDetails, transaction *BankTransaction) {
+	transaction.Payee = details.Debtor + details.DebtorParty
+	if transaction.Amount < 0 {
+		transaction.Payee = details.Creditor + details.CreditorParty
+	}
+	if len(details.Remittance) != 0 {
+		transaction.Memo = strings.Join(details.Remittance, " ")
+	}
+}
+
+func parseCAMTAmount(ID string, amount camtAmount, creditDebit string, statement *BankStatement) (Amount, error) {
+	if statement.Currency == "" {
+		statement.Currency = amount.Currency
+	}
+	if amount.Currency != "" && amount.Currency != statement.Currency {
+		return 0, fErrInvalidBankStatement(fmt.Sprintf("the transaction %q is in %v instead of %v", ID, amount.Currency, statement.Currency))
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(amount.Value), 64)
+	if err != nil || value < 0 {
+		return 0, fErrInvalidBankStatement(fmt.Sprintf("the transaction %q has the amount %q", ID, amount.Value))
+	}
+	switch creditDebit {
+	case "CRDT":
+		return Amount(value), nil
+	case "DBIT":
+		return Amount(-value), nil
+	}
+	return 0, fErrInvalidBankStatement(fmt.Sprintf("the transaction %q has the CdtDbtInd %q", ID, creditDebit))
+}
+
+// parseCAMTTime parses the date of an element like 2024-03-02 or its time like 2024-03-02T10:00:00+01:00,
+// a time without a zone is in UTC.
+func parseCAMTTime(reference, date, dateTime string) (TimeUnix, error) {
+	value := strings.TrimSpace(dateTime)
+	layouts := []string{time.RFC3339Nano, "2006-01-02T15:04:05"}
+	if value == "" {
+		value = strings.TrimSpace(date)
+		layouts = []string{time.DateOnly}
+	}
+
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t.UnixMicro(), nil
+		}
+	}
+	return 0, fErrInvalidBankStatement(fmt.Sprintf("the entry %q has the date %q", reference, value))
+}
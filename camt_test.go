@@ -0,0 +1,165 @@
+package accounting
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_ReadCAMT053(t *testing.T) {
+	type input struct {
+		file string
+	}
+	type output struct {
+		BankStatement BankStatement
+		err           error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>M-1</MsgId></GrpHdr>
+    <Stmt>
+      <Id>S-1</Id>
+      <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy></Acct>
+      <Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-03-01</Dt></Dt></Bal>
+      <Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">1530.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-03-10</Dt></Dt></Bal>
+      <Ntry>
+        <NtryRef>N-1</NtryRef>
+        <Amt Ccy="EUR">1500.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2024-03-02T10:30:00+01:00</DtTm></BookgDt>
+        <AcctSvcrRef>B-1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties><Dbtr><Pty><Nm>ACME GmbH</Nm></Pty></Dbtr></RltdPties>
+          <RmtInf><Ustrd>Invoice 17</Ustrd><Ustrd>March</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <NtryRef>N-2</NtryRef>
+        <Amt Ccy="EUR">70.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2024-03-03</Dt></BookgDt>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>E-1</EndToEndId></Refs>
+            <Amt Ccy="EUR">30.00</Amt>
+            <RltdPties><Cdtr><Nm>Power Co</Nm></Cdtr></RltdPties>
+          </TxDtls>
+          <TxDtls>
+            <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+            <Amt Ccy="EUR">40.00</Amt>
+            <RltdPties><Cdtr><Nm>Water Co</Nm></Cdtr></RltdPties>
+          </TxDtls>
+        </NtryDtls>
+        <AddtlNtryInf>Direct debits</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2024-03-04</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`,
+			},
+			output: output{
+				BankStatement: BankStatement{
+					Account:        "DE89370400440532013000",
+					Currency:       "EUR",
+					ClosingBalance: BankBalance{1710115199999999, 1530},
+					Transactions: []BankTransaction{
+						{ID: "B-1", TimeUnix: 1709371800000000, Amount: 1500, Payee: "ACME GmbH", Memo: "Invoice 17 March"},
+						{ID: "E-1", TimeUnix: 1709424000000000, Amount: -30, Payee: "Power Co", Memo: "Direct debits"},
+						{ID: "N-2/2", TimeUnix: 1709424000000000, Amount: -40, Payee: "Water Co", Memo: "Direct debits"},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<Document><BkToCstmrStmt><Stmt>
+<Acct><Id><Othr><Id>778</Id></Othr></Id></Acct>
+<Ntry><NtryRef>N-1</NtryRef><Amt Ccy="USD">12.5</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2024-03-02</Dt></BookgDt></Ntry>
+</Stmt></BkToCstmrStmt></Document>`,
+			},
+			output: output{
+				BankStatement: BankStatement{
+					Account:      "778",
+					Currency:     "USD",
+					Transactions: []BankTransaction{{ID: "N-1", TimeUnix: 1709337600000000, Amount: 12.5}},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<Document><BkToCstmrStmt><Stmt>
+<Acct><Ccy>EUR</Ccy></Acct>
+<Ntry><NtryRef>N-1</NtryRef><Amt Ccy="USD">12.5</Amt><CdtDbtInd>CRDT</CdtDbtInd><BookgDt><Dt>2024-03-02</Dt></BookgDt></Ntry>
+</Stmt></BkToCstmrStmt></Document>`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because the transaction \"N-1\" is in USD instead of EUR"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<Document><BkToCstmrStmt><Stmt>
+<Ntry><NtryRef>N-1</NtryRef><Amt>12.5</Amt><CdtDbtInd>CRDT</CdtDbtInd><BookgDt><Dt>02.03.2024</Dt></BookgDt></Ntry>
+</Stmt></BkToCstmrStmt></Document>`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because the entry \"N-1\" has the date \"02.03.2024\""),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<Document><BkToCstmrStmt><Stmt>
+<Ntry><NtryRef>N-1</NtryRef><Amt>12.5</Amt><CdtDbtInd>RVSL</CdtDbtInd><BookgDt><Dt>2024-03-02</Dt></BookgDt></Ntry>
+</Stmt></BkToCstmrStmt></Document>`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because the transaction \"N-1\" has the CdtDbtInd \"RVSL\""),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<Document><BkToCstmrStmt><GrpHdr/></BkToCstmrStmt></Document>`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because it has no Stmt element"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.BankStatement, output.err = ReadCAMT053(strings.NewReader(tt.input.file))
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
@@ -0,0 +1,152 @@
+package accounting
+
+import (
+	"crypto/sha256"
+	"maps"
+	"slices"
+)
+
+// Hash is a SHA-256 hash.
+type Hash [sha256.Size]byte
+
+// Checkpoint is the state of the journal after a verified entry, so the next verification starts after it.
+type Checkpoint struct {
+	EntryID     // the ID of the last verified entry, zero if no entry is verified
+	TimeUnix    // the time of the last verified entry
+	Sequence    // the sequence of the last verified entry
+	Hash        // the Hash of the last verified entry, it is chained to the hashes of all the entries posted before it
+	Inventories AccountIDAndInventory
+}
+
+// CheckpointStore is the storage of the checkpoints.
+// GetLastCheckpoint returns the last checkpoint that SetCheckpoint stored and false if there is none.
+type CheckpointStore interface {
+	GetLastCheckpoint() (Checkpoint, bool, error)
+	SetCheckpoint(Checkpoint) error
+}
+
+// VerifyJournal is CheckAllTheJournal that starts after the last checkpoint instead of the first entry.
+//
+// Parameters:
+//   - dbCommand: The storage of the journal and the inventories
+//   - store: The storage of the checkpoints
+//   - isFull: true to replay all the journal like for an audit, then the last checkpoint is checked against the journal
+//
+// The entries after the last checkpoint are processed against the inventories of the checkpoint,
+// then all the inventories are written with SetInventory and a new checkpoint is stored.
+// If an entry posted after the checkpoint is before it in time, like an entry inserted with InsertToJournal,
+// the inventories of the checkpoint are stale and all the journal is replayed like in full mode.
+// In full mode the inventories of the last checkpoint are checked only if no entry posted after it is before it.
+//
+// The hash chain of the entries is checked too, like CheckAllTheJournal does: in full mode for all the journal,
+// otherwise for the last entry of the checkpoint and the entries after it, whose hashes should be chained to the hashes
+// of the entries posted before them.
+//
+// Returns the new checkpoint, or an Error with the name ErrCheckpointMismatch if the entries
+// until the last checkpoint are not the entries that were verified, or an Error with the name
+// ErrHashChainBroken, then nothing is written.
+func VerifyJournal(dbCommand DB, store CheckpointStore, isFull bool) (Checkpoint, error) {
+	last, _, err := store.GetLastCheckpoint()
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	current := Checkpoint{Inventories: make(AccountIDAndInventory)}
+	journalRange := JournalRange{}
+	if !isFull && last.EntryID != 0 {
+		current = last
+		current.Inventories = cloneInventories(last.Inventories)
+		journalRange.From = last.TimeUnix
+	}
+
+	var isLastFound bool
+	isStale := false       // an entry posted after the last checkpoint is before it
+	var newEntries EntryID // the entries posted after the last checkpoint that are replayed after it
+	chain := make(hashChain)
+	for entry, err := range dbCommand.Journal(journalRange) {
+		if err != nil {
+			return Checkpoint{}, err
+		}
+		if !isFull && last.EntryID != 0 && (entry.TimeUnix == last.TimeUnix && entry.Sequence <= last.Sequence) {
+			if entry.Sequence == last.Sequence {
+				if entry.ID != last.EntryID || entry.Hash != last.Hash {
+					return Checkpoint{}, fErrCheckpointMismatch(last)
+				}
+				chain.add(entry)
+				isLastFound = true
+			}
+			continue
+		}
+		chain.add(entry)
+		err = checkJournalOrder(current.EntryID == 0, current.TimeUnix, current.Sequence, entry)
+		if err != nil {
+			return Checkpoint{}, err
+		}
+
+		current.Inventories, err = CheckAndProcessDoubleEntry(current.TimeUnix, entry, current.Inventories)
+		if err != nil {
+			return Checkpoint{}, err
+		}
+		current.EntryID = entry.ID
+		current.TimeUnix = entry.TimeUnix
+		current.Sequence = entry.Sequence
+		current.Hash = entry.Hash
+		if last.EntryID != 0 && entry.ID > last.EntryID {
+			newEntries++
+			isStale = isStale || (isFull && !isLastFound)
+		}
+
+		if isFull && entry.ID == last.EntryID {
+			if current.Hash != last.Hash || (!isStale && !maps.EqualFunc(current.Inventories, last.Inventories, slices.Equal)) {
+				return Checkpoint{}, fErrCheckpointMismatch(last)
+			}
+			isLastFound = true
+		}
+	}
+
+	if last.EntryID != 0 && !isLastFound {
+		return Checkpoint{}, fErrCheckpointMismatch(last)
+	}
+
+	if !isFull && last.EntryID != 0 {
+		lastID, err := dbCommand.GetLastEntryID()
+		if err != nil {
+			return Checkpoint{}, err
+		}
+		if newEntries != lastID-last.EntryID {
+			return VerifyJournal(dbCommand, store, true)
+		}
+	}
+
+	if isFull {
+		err = chain.check()
+	} else {
+		err = chain.checkAfter(dbCommand)
+	}
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	for ID, inv := range current.Inventories {
+		err := dbCommand.SetInventory(ID, inv)
+		if err != nil {
+			return Checkpoint{}, err
+		}
+	}
+
+	current.Inventories = cloneInventories(current.Inventories)
+	err = store.SetCheckpoint(current)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	return current, nil
+}
+
+// cloneInventories returns a copy of the inventories that shares nothing with them.
+func cloneInventories(IDAndInventory AccountIDAndInventory) AccountIDAndInventory {
+	result := make(AccountIDAndInventory, len(IDAndInventory))
+	for ID, inv := range IDAndInventory {
+		result[ID] = slices.Clone(inv)
+	}
+	return result
+}
@@ -0,0 +1,176 @@
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+)
+
+type myCheckpointStore struct {
+	checkpoints []Checkpoint
+}
+
+func (s *myCheckpointStore) GetLastCheckpoint() (Checkpoint, bool, error) {
+	if len(s.checkpoints) == 0 {
+		return Checkpoint{}, false, nil
+	}
+	return s.checkpoints[len(s.checkpoints)-1], true, nil
+}
+func (s *myCheckpointStore) SetCheckpoint(value Checkpoint) error {
+	s.checkpoints = append(s.checkpoints, value)
+	return nil
+}
+
+// countingDB counts the entries that are read from the journal.
+type countingDB struct {
+	*myDB
+	read int
+}
+
+func (s *countingDB) Journal(r JournalRange) iter.Seq2[AccountingEntry, error] {
+	return func(yield func(AccountingEntry, error) bool) {
+		for entry, err := range s.myDB.Journal(r) {
+			s.read++
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
+
+func Test_VerifyJournal(t *testing.T) {
+	kk := &myDB{myInv: make(AccountIDAndInventory)}
+	db := &countingDB{myDB: kk}
+	var store myCheckpointStore
+
+	entries := []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, 3001, 5, 50}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, kk)
+		fTest(err, nil)
+	}
+
+	checkpoint, err := VerifyJournal(db, &store, false)
+	fTest(err, nil)
+	fTest(db.read, 3)
+	fTest(checkpoint.EntryID, 3)
+	fTest(checkpoint.TimeUnix, 2)
+	fTest(checkpoint.Sequence, 1)
+	fTest(checkpoint.Inventories, kk.myInv)
+
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 3, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, 3001, 5, 50}}}, kk)
+	fTest(err, nil)
+	myInvExpected := kk.myInv
+	kk.myInv = make(AccountIDAndInventory)
+
+	// only the entries at the time of the checkpoint and after it are read
+	db.read = 0
+	checkpoint, err = VerifyJournal(db, &store, false)
+	fTest(err, nil)
+	fTest(db.read, 3)
+	fTest(checkpoint.EntryID, 4)
+	fTest(kk.myInv, myInvExpected)
+
+	db.read = 0
+	full, err := VerifyJournal(db, &store, true)
+	fTest(err, nil)
+	fTest(db.read, 4)
+	fTest(full, checkpoint)
+
+	fTest(checkpoint.Hash, kk.myEntries[3].Hash)
+
+	// an entry before the checkpoint changed
+	kk.myEntries[1].Description = "changed"
+	_, err = VerifyJournal(db, &store, true)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 2, its content, its order or the entry posted before it was changed"))
+	fTest(errors.Is(err, ErrConflict), true)
+	kk.myEntries[1].Description = ""
+
+	// the hash of the entry of the checkpoint changed
+	kk.myEntries[3].Hash[0]++
+	_, err = VerifyJournal(db, &store, false)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrCheckpointMismatch : the journal until the entry with ID 4 is not the journal of the last checkpoint"))
+	kk.myEntries[3].Hash[0]--
+
+	// the entry of the checkpoint is not in the journal anymore
+	kk.myEntries[3].ID = 9
+	_, err = VerifyJournal(db, &store, false)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrCheckpointMismatch : the journal until the entry with ID 4 is not the journal of the last checkpoint"))
+	kk.myEntries[3].ID = 4
+
+	// the entries after the checkpoint are chained to it
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 4, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 10}, {WAC, 2001, 10, 10}}}, kk)
+	fTest(err, nil)
+	kk.myEntries[4].PreviousHash = HashEntry(kk.myEntries[2])
+	kk.myEntries[4].Hash = HashEntry(kk.myEntries[4])
+	_, err = VerifyJournal(db, &store, false)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 5, its content, its order or the entry posted before it was changed"))
+	kk.myEntries[4].PreviousHash = kk.myEntries[3].Hash
+	kk.myEntries[4].Hash = HashEntry(kk.myEntries[4])
+
+	kk.myEntries[4].Description = "changed"
+	_, err = VerifyJournal(db, &store, false)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 5, its content, its order or the entry posted before it was changed"))
+	kk.myEntries[4].Description = ""
+	myInvExpected = kk.myInv
+	kk.myInv = make(AccountIDAndInventory)
+	checkpoint, err = VerifyJournal(db, &store, false)
+	fTest(err, nil)
+	fTest(checkpoint.EntryID, 5)
+	fTest(kk.myInv, myInvExpected)
+	full, err = VerifyJournal(db, &store, true)
+	fTest(err, nil)
+
+	// a zero checkpoint starts again from the first entry
+	fTest(store.SetCheckpoint(Checkpoint{}), nil)
+	db.read = 0
+	checkpoint, err = VerifyJournal(db, &store, false)
+	fTest(err, nil)
+	fTest(db.read, 5)
+	fTest(checkpoint, full)
+}
+
+func Test_VerifyJournal_afterInsert(t *testing.T) {
+	kk := &myDB{myInv: make(AccountIDAndInventory)}
+	var store myCheckpointStore
+
+	for _, entry := range []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+	} {
+		_, err := AddToJournal(entry, kk)
+		fTest(err, nil)
+	}
+	_, err := VerifyJournal(kk, &store, false)
+	fTest(err, nil)
+
+	// the entry inserted before the checkpoint makes its inventories stale, so all the journal is replayed
+	_, issues, err := InsertToJournal(AccountingEntry{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}}, kk)
+	fTest(err, nil)
+	fTest(len(issues), 0)
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 6, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 1}, {WAC, 2001, 1, 1}}}, kk)
+	fTest(err, nil)
+	fTest(kk.myInv[2001], Inventory{{6, 799, 799}})
+
+	myInvExpected := cloneInventories(kk.myInv)
+	checkpoint, err := VerifyJournal(kk, &store, false)
+	fTest(err, nil)
+	fTest(checkpoint.EntryID, 4)
+	fTest(kk.myInv, myInvExpected)
+	drifts, err := FindInventoryDrift(kk)
+	fTest(err, nil)
+	fTest(len(drifts), 0)
+
+	// the full mode doesn't fail on the stale checkpoint
+	fTest(store.SetCheckpoint(store.checkpoints[0]), nil)
+	full, err := VerifyJournal(kk, &store, true)
+	fTest(err, nil)
+	fTest(full, checkpoint)
+	fTest(kk.myInv, myInvExpected)
+}
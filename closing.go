@@ -0,0 +1,143 @@
+package accounting
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+// MakeClosingEntry builds the year-end entry that zeros every temporary account
+// (revenues and expenses) into the retained earnings account.
+//
+// Parameters:
+//   - timeUnix: The time of the closing entry, usually the last moment of the fiscal year
+//   - retainedEarnings: The equity account that receives the net income
+//   - accumulatedDeficit: The equity account that receives the net loss, a debit nature account like a contra-equity account
+//   - temporaryAccounts: The balances of the revenue and expense accounts at the end of the year
+//
+// Every temporary account with a balance gets a NONE line that takes out its whole quantity and amount,
+// so the account is left empty. The difference between the closed revenues and expenses is put on the
+// retained earnings account when the year made a profit and on the accumulated deficit account when it made a loss,
+// so a loss never takes the retained earnings below zero. With the usual natures of these accounts both lines are INFLOW,
+// a line on the other side of the nature of its account is NONE and needs the balance to take out.
+// The lines are sorted by account ID so the same balances always give the same entry,
+// and the entry has IsClosing set so reports can leave it out.
+//
+// Returns an error if the retained earnings or the accumulated deficit account is one of the temporary accounts
+// or if none of the temporary accounts has a balance.
+func MakeClosingEntry(timeUnix TimeUnix, retainedEarnings AccountID, accumulatedDeficit AccountID, temporaryAccounts AccountIDAndInventory) (AccountingEntry, error) {
+	for _, ID := range []AccountID{retainedEarnings, accumulatedDeficit} {
+		if _, ok := temporaryAccounts[ID]; ok {
+			return AccountingEntry{}, newError(ErrDuplicateAccountInEntry, "duplicate account ID %v in entry", ID)
+		}
+	}
+
+	entry := AccountingEntry{
+		TimeUnix:  timeUnix,
+		IsClosing: true,
+		Metadata:  Metadata{Description: "closing entry"},
+	}
+	var totalDebit, totalCredit Amount
+	for _, ID := range slices.Sorted(maps.Keys(temporaryAccounts)) {
+		qty, amt := GetTotalInventory(temporaryAccounts[ID])
+		if qty == 0 && amt == 0 {
+			continue
+		}
+
+		entry.DoubleEntry = append(entry.DoubleEntry, SingleEntry{NONE, ID, qty, amt})
+		if GetStatus(NONE, ID) {
+			totalDebit += amt
+		} else {
+			totalCredit += amt
+		}
+	}
+
+	if len(entry.DoubleEntry) == 0 {
+		return AccountingEntry{}, newError(ErrNoBalanceToClose, "there is no balance to close in the temporary accounts")
+	}
+
+	// the result line takes the side that balances the entry, a credit for a profit and a debit for a loss
+	if totalDebit != totalCredit {
+		isDebit := totalDebit < totalCredit
+		ID := retainedEarnings
+		if isDebit {
+			ID = accumulatedDeficit
+		}
+
+		costFlowType := INFLOW
+		if GetStatus(INFLOW, ID) != IsDebit(isDebit) {
+			costFlowType = NONE
+		}
+		entry.DoubleEntry = append(entry.DoubleEntry, SingleEntry{costFlowType, ID, 0, Amount(math.Abs(float64(totalDebit - totalCredit)))})
+	}
+
+	return entry, nil
+}
+
+// MakeOpeningEntry builds the entry that carries the balance sheet accounts forward into a new set of books.
+// Each account with a balance gets one INFLOW line with its total quantity and amount and its cost layers
+// as the Layers of the entry, so the layers keep their times and their prices and the cost flow types
+// take them out in the new books like in the old ones.
+// The entry balances only if the given balances do, which is the case after the temporary
+// accounts have been closed with MakeClosingEntry.
+func MakeOpeningEntry(timeUnix TimeUnix, balanceSheetAccounts AccountIDAndInventory) (AccountingEntry, error) {
+	entry := AccountingEntry{TimeUnix: timeUnix, Layers: make(AccountIDAndInventory)}
+	for _, ID := range slices.Sorted(maps.Keys(balanceSheetAccounts)) {
+		inv := removeZeros(balanceSheetAccounts[ID])
+		qty, amt := GetTotalInventory(inv)
+		if qty == 0 && amt == 0 {
+			continue
+		}
+		entry.DoubleEntry = append(entry.DoubleEntry, SingleEntry{INFLOW, ID, qty, amt})
+		entry.Layers[ID] = slices.Clone(inv)
+	}
+
+	if len(entry.DoubleEntry) == 0 {
+		return AccountingEntry{}, newError(ErrNoBalanceToClose, "there is no balance to carry forward")
+	}
+
+	return entry, nil
+}
+
+// CloseFiscalYear computes the balances of the temporary accounts at yearEnd by replaying the journal until it,
+// builds the closing entry with MakeClosingEntry and inserts it in the journal at yearEnd with InsertToJournal,
+// so the year can be closed after entries of the next year are posted.
+//
+// Returns the posted closing entry with its ID, or the issues of InsertToJournal and an error
+// if the closing entry makes later entries invalid, like an entry that takes out of a closed account.
+func CloseFiscalYear(yearEnd TimeUnix, retainedEarnings AccountID, accumulatedDeficit AccountID, temporaryAccounts []AccountID, dbCommand DB) (AccountingEntry, []ReplayIssue, error) {
+	var lastTimeUnix TimeUnix
+	IDAndInventory := make(AccountIDAndInventory)
+	for entry, err := range dbCommand.Journal(JournalRange{To: yearEnd}) {
+		if err != nil {
+			return AccountingEntry{}, nil, err
+		}
+
+		IDAndInventory, err = CheckAndProcessDoubleEntry(lastTimeUnix, entry, IDAndInventory)
+		if err != nil {
+			return AccountingEntry{}, nil, err
+		}
+		lastTimeUnix = entry.TimeUnix
+	}
+
+	balances := make(AccountIDAndInventory)
+	for _, ID := range temporaryAccounts {
+		balances[ID] = IDAndInventory[ID]
+	}
+
+	entry, err := MakeClosingEntry(yearEnd, retainedEarnings, accumulatedDeficit, balances)
+	if err != nil {
+		return AccountingEntry{}, nil, err
+	}
+
+	ID, issues, err := InsertToJournal(entry, dbCommand)
+	if err != nil {
+		return AccountingEntry{}, issues, err
+	}
+
+	entry, _, err = dbCommand.GetEntry(ID)
+	if err != nil {
+		return AccountingEntry{}, nil, err
+	}
+	return entry, nil, nil
+}
@@ -0,0 +1,266 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_MakeClosingEntry(t *testing.T) {
+	type input struct {
+		TimeUnix           TimeUnix
+		RetainedEarnings   AccountID
+		AccumulatedDeficit AccountID
+		TemporaryAccounts  AccountIDAndInventory
+	}
+	type output struct {
+		AccountingEntry AccountingEntry
+		err             error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				TimeUnix:           100,
+				RetainedEarnings:   -3000,
+				AccumulatedDeficit: 3900,
+				TemporaryAccounts: AccountIDAndInventory{
+					-4001: Inventory{{3, 5, 80}},
+					3001:  Inventory{{3, 5, 50}},
+				},
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{
+					TimeUnix:  100,
+					IsClosing: true,
+					Metadata:  Metadata{Description: "closing entry"},
+					DoubleEntry: DoubleEntry{
+						{NONE, -4001, 5, 80},
+						{NONE, 3001, 5, 50},
+						{INFLOW, -3000, 0, 30},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				TimeUnix:           100,
+				RetainedEarnings:   -3000,
+				AccumulatedDeficit: 3900,
+				TemporaryAccounts: AccountIDAndInventory{
+					-4001: Inventory{{3, 5, 50}},
+					3001:  Inventory{{3, 5, 20}, {4, 5, 60}},
+					3002:  nil,
+				},
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{
+					TimeUnix:  100,
+					IsClosing: true,
+					Metadata:  Metadata{Description: "closing entry"},
+					DoubleEntry: DoubleEntry{
+						{NONE, -4001, 5, 50},
+						{NONE, 3001, 10, 80},
+						{INFLOW, 3900, 0, 30},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				TimeUnix:           100,
+				RetainedEarnings:   -3000,
+				AccumulatedDeficit: 3900,
+				TemporaryAccounts: AccountIDAndInventory{
+					-4001: Inventory{{3, 5, 50}},
+					3001:  Inventory{{3, 5, 50}},
+				},
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{
+					TimeUnix:  100,
+					IsClosing: true,
+					Metadata:  Metadata{Description: "closing entry"},
+					DoubleEntry: DoubleEntry{
+						{NONE, -4001, 5, 50},
+						{NONE, 3001, 5, 50},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				TimeUnix:           100,
+				RetainedEarnings:   -3000,
+				AccumulatedDeficit: 3900,
+				TemporaryAccounts: AccountIDAndInventory{
+					-4001: nil,
+				},
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrNoBalanceToClose : there is no balance to close in the temporary accounts"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				TimeUnix:           100,
+				RetainedEarnings:   -3000,
+				AccumulatedDeficit: 3900,
+				TemporaryAccounts: AccountIDAndInventory{
+					-3000: Inventory{{3, 5, 50}},
+				},
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrDuplicateAccountInEntry : duplicate account ID -3000 in entry"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				TimeUnix:           100,
+				RetainedEarnings:   -3000,
+				AccumulatedDeficit: 3900,
+				TemporaryAccounts: AccountIDAndInventory{
+					3900: Inventory{{3, 5, 50}},
+				},
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrDuplicateAccountInEntry : duplicate account ID 3900 in entry"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				TimeUnix:           100,
+				RetainedEarnings:   -3000,
+				AccumulatedDeficit: -3000,
+				TemporaryAccounts: AccountIDAndInventory{
+					-4001: Inventory{{3, 5, 50}},
+					3001:  Inventory{{3, 5, 80}},
+				},
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{
+					TimeUnix:  100,
+					IsClosing: true,
+					Metadata:  Metadata{Description: "closing entry"},
+					DoubleEntry: DoubleEntry{
+						{NONE, -4001, 5, 50},
+						{NONE, 3001, 5, 80},
+						{NONE, -3000, 0, 30},
+					},
+				},
+				err: nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.AccountingEntry, output.err = MakeClosingEntry(tt.input.TimeUnix, tt.input.RetainedEarnings, tt.input.AccumulatedDeficit, tt.input.TemporaryAccounts)
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_MakeOpeningEntry(t *testing.T) {
+	balances := AccountIDAndInventory{
+		-1001: Inventory{{1, 0, 1000}},
+		-3000: Inventory{{100, 0, 30}},
+		1001:  Inventory{{2, 45, 450}},
+		2001:  Inventory{{2, 500, 500}, {3, 80, 80}},
+		3001:  nil,
+	}
+	entry, err := MakeOpeningEntry(200, balances)
+	fTest(err, nil)
+	fTest(entry, AccountingEntry{
+		TimeUnix: 200,
+		DoubleEntry: DoubleEntry{
+			{INFLOW, -3000, 0, 30},
+			{INFLOW, -1001, 0, 1000},
+			{INFLOW, 1001, 45, 450},
+			{INFLOW, 2001, 580, 580},
+		},
+		Layers: AccountIDAndInventory{
+			-3000: Inventory{{100, 0, 30}},
+			-1001: Inventory{{1, 0, 1000}},
+			1001:  Inventory{{2, 45, 450}},
+			2001:  Inventory{{2, 500, 500}, {3, 80, 80}},
+		},
+	})
+
+	// the new books have the same layers
+	IDAndInventory, err := CheckAndProcessDoubleEntry(0, entry, make(AccountIDAndInventory))
+	fTest(err, nil)
+	delete(balances, 3001)
+	fTest(IDAndInventory, balances)
+}
+
+func Test_CloseFiscalYear(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 50, 500}, {WAC, 2001, 500, 500}}},
+		{TimeUnix: 3, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, 3001, 5, 50}, {INFLOW, 2001, 80, 80}, {INFLOW, -4001, 5, 80}}},
+		{TimeUnix: 6, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, 3001, 5, 50}, {INFLOW, 2001, 10, 10}, {INFLOW, -4001, 5, 10}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	// the year is closed after an entry of the next year
+	entry, issues, err := CloseFiscalYear(4, -3000, 3900, []AccountID{-4001, 3001}, &kk)
+	fTest(err, nil)
+	fTest(issues, nil)
+	fTest(entry, AccountingEntry{
+		ID:        5,
+		TimeUnix:  4,
+		IsClosing: true,
+		Metadata:  Metadata{Description: "closing entry"},
+		DoubleEntry: DoubleEntry{
+			{NONE, -4001, 5, 80},
+			{NONE, 3001, 5, 50},
+			{INFLOW, -3000, 0, 30},
+		},
+		PreviousHash: kk.myEntries[4].Hash,
+		Hash:         entry.Hash,
+	})
+	fTest(entry.Hash, HashEntry(entry))
+	fTest(kk.myEntries[3].ID, 5)
+	fTest(kk.myInv[-4001], Inventory{{6, 5, 10}})
+	fTest(kk.myInv[3001], Inventory{{6, 5, 50}})
+	fTest(kk.myInv[-3000], Inventory{{4, 0, 30}})
+
+	// the next year makes a loss, it goes to the accumulated deficit even if the retained earnings can't take it
+	entry, issues, err = CloseFiscalYear(7, -3000, 3900, []AccountID{-4001, 3001}, &kk)
+	fTest(err, nil)
+	fTest(issues, nil)
+	fTest(entry.DoubleEntry, DoubleEntry{
+		{NONE, -4001, 5, 10},
+		{NONE, 3001, 5, 50},
+		{INFLOW, 3900, 0, 40},
+	})
+	fTest(kk.myInv[-3000], Inventory{{4, 0, 30}})
+	fTest(kk.myInv[3900], Inventory{{7, 0, 40}})
+
+	_, _, err = CloseFiscalYear(8, -3000, 3900, []AccountID{-4001, 3001}, &kk)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrNoBalanceToClose : there is no balance to close in the temporary accounts"))
+}
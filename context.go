@@ -0,0 +1,176 @@
+package accounting
+
+import (
+	"context"
+	"iter"
+)
+
+// ContextDB is DB with a context.Context for every method, so a slow backend call can be cancelled or time-limited.
+// The methods do the same as the methods of DB with the same names.
+type ContextDB interface {
+	GetInventory(context.Context, AccountID) (Inventory, error)
+	SetInventory(context.Context, AccountID, Inventory) error
+	GetLastEntry(context.Context) (AccountingEntry, error)
+	GetLastEntryID(context.Context) (EntryID, error)
+	GetEntry(context.Context, EntryID) (AccountingEntry, bool, error)
+	SetEntry(context.Context, AccountingEntry) error
+	Journal(context.Context, JournalRange) iter.Seq2[AccountingEntry, error]
+}
+
+// ContextIdempotencyDB is IdempotencyDB with a context.Context, AddToJournalContext needs it for an entry with an IdempotencyKey.
+type ContextIdempotencyDB interface {
+	ContextDB
+	GetEntryByIdempotencyKey(context.Context, string) (AccountingEntry, bool, error)
+}
+
+// NewContextDB returns a ContextDB for an existing DB, it is a ContextIdempotencyDB if the DB is an IdempotencyDB.
+// Every method returns the error of the context without calling the DB if the context is done,
+// and the iterator of Journal yields the error of the context and stops when the context is done.
+func NewContextDB(dbCommand DB) ContextDB {
+	if _, ok := dbCommand.(IdempotencyDB); ok {
+		return contextIdempotencyDB{contextDB{dbCommand}}
+	}
+	return contextDB{dbCommand}
+}
+
+type contextDB struct {
+	DB
+}
+
+func (d contextDB) GetInventory(ctx context.Context, ID AccountID) (Inventory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return d.DB.GetInventory(ID)
+}
+
+func (d contextDB) SetInventory(ctx context.Context, ID AccountID, inv Inventory) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.DB.SetInventory(ID, inv)
+}
+
+func (d contextDB) GetLastEntry(ctx context.Context) (AccountingEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return AccountingEntry{}, err
+	}
+	return d.DB.GetLastEntry()
+}
+
+func (d contextDB) GetLastEntryID(ctx context.Context) (EntryID, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return d.DB.GetLastEntryID()
+}
+
+func (d contextDB) GetEntry(ctx context.Context, ID EntryID) (AccountingEntry, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return AccountingEntry{}, false, err
+	}
+	return d.DB.GetEntry(ID)
+}
+
+func (d contextDB) SetEntry(ctx context.Context, entry AccountingEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.DB.SetEntry(entry)
+}
+
+func (d contextDB) Journal(ctx context.Context, journalRange JournalRange) iter.Seq2[AccountingEntry, error] {
+	return func(yield func(AccountingEntry, error) bool) {
+		if err := ctx.Err(); err != nil {
+			yield(AccountingEntry{}, err)
+			return
+		}
+
+		for entry, err := range d.DB.Journal(journalRange) {
+			if err == nil {
+				err = ctx.Err()
+			}
+			if !yield(entry, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+type contextIdempotencyDB struct {
+	contextDB
+}
+
+func (d contextIdempotencyDB) GetEntryByIdempotencyKey(ctx context.Context, key string) (AccountingEntry, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return AccountingEntry{}, false, err
+	}
+	return d.DB.(IdempotencyDB).GetEntryByIdempotencyKey(key)
+}
+
+// boundDB is a DB that calls a ContextDB with its context.
+// The reads get the context and stop when it is done. The writes get the context without its cancellation,
+// because AddToJournal writes the entry and then the inventories, and a cancellation between them
+// would leave the inventories behind the journal.
+type boundDB struct {
+	ctx context.Context
+	ContextDB
+}
+
+// bind returns the DB that calls the ContextDB with the context, it is an IdempotencyDB if the ContextDB is a ContextIdempotencyDB.
+func bind(ctx context.Context, dbCommand ContextDB) DB {
+	if _, ok := dbCommand.(ContextIdempotencyDB); ok {
+		return boundIdempotencyDB{boundDB{ctx, dbCommand}}
+	}
+	return boundDB{ctx, dbCommand}
+}
+
+func (d boundDB) GetInventory(ID AccountID) (Inventory, error) {
+	return d.ContextDB.GetInventory(d.ctx, ID)
+}
+
+func (d boundDB) SetInventory(ID AccountID, inv Inventory) error {
+	return d.ContextDB.SetInventory(context.WithoutCancel(d.ctx), ID, inv)
+}
+
+func (d boundDB) GetLastEntry() (AccountingEntry, error) {
+	return d.ContextDB.GetLastEntry(d.ctx)
+}
+
+func (d boundDB) GetLastEntryID() (EntryID, error) {
+	return d.ContextDB.GetLastEntryID(d.ctx)
+}
+
+func (d boundDB) GetEntry(ID EntryID) (AccountingEntry, bool, error) {
+	return d.ContextDB.GetEntry(d.ctx, ID)
+}
+
+func (d boundDB) SetEntry(entry AccountingEntry) error {
+	return d.ContextDB.SetEntry(context.WithoutCancel(d.ctx), entry)
+}
+
+func (d boundDB) Journal(journalRange JournalRange) iter.Seq2[AccountingEntry, error] {
+	return d.ContextDB.Journal(d.ctx, journalRange)
+}
+
+type boundIdempotencyDB struct {
+	boundDB
+}
+
+func (d boundIdempotencyDB) GetEntryByIdempotencyKey(key string) (AccountingEntry, bool, error) {
+	return d.ContextDB.(ContextIdempotencyDB).GetEntryByIdempotencyKey(d.ctx, key)
+}
+
+// AddToJournalContext is AddToJournal with a context.
+// It stops with the error of the context if the context is done before the entry is written,
+// after that the entry and the inventories are written even if the context is done.
+func AddToJournalContext(ctx context.Context, entry AccountingEntry, dbCommand ContextDB) (EntryID, error) {
+	return AddToJournal(entry, bind(ctx, dbCommand))
+}
+
+// CheckAllTheJournalContext is CheckAllTheJournal with a context.
+// It stops with the error of the context if the context is done before all the journal is replayed,
+// then no inventory is written.
+func CheckAllTheJournalContext(ctx context.Context, dbCommand ContextDB) error {
+	return CheckAllTheJournal(bind(ctx, dbCommand))
+}
@@ -0,0 +1,73 @@
+package accounting
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+)
+
+func Test_AddToJournalContext(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+	db := NewContextDB(&kk)
+
+	ID, err := AddToJournalContext(context.Background(), AccountingEntry{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 10, 100}}}, db)
+	fTest(err, nil)
+	fTest(ID, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = AddToJournalContext(ctx, AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 10, 100}}}, db)
+	fTest(errors.Is(err, context.Canceled), true)
+	fTest(len(kk.myEntries), 1)
+	fTest(kk.myInv[1001], Inventory{{1, 10, 100}})
+}
+
+// cancelingDB cancels its context after some entries of the journal.
+type cancelingDB struct {
+	*myDB
+	cancel       context.CancelFunc
+	iterLeft     int
+	setInventory int
+}
+
+func (s *cancelingDB) Journal(r JournalRange) iter.Seq2[AccountingEntry, error] {
+	return func(yield func(AccountingEntry, error) bool) {
+		for entry, err := range s.myDB.Journal(r) {
+			s.iterLeft--
+			if s.iterLeft == 0 {
+				s.cancel()
+			}
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
+
+func (s *cancelingDB) SetInventory(key AccountID, value Inventory) error {
+	s.setInventory++
+	return s.myDB.SetInventory(key, value)
+}
+
+func Test_CheckAllTheJournalContext(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+	for i := range 3 {
+		_, err := AddToJournal(AccountingEntry{TimeUnix: TimeUnix(i + 1), DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 10, 100}}}, &kk)
+		fTest(err, nil)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	db := &cancelingDB{myDB: &kk, cancel: cancel, iterLeft: 2}
+	err := CheckAllTheJournalContext(ctx, NewContextDB(db))
+	fTest(errors.Is(err, context.Canceled), true)
+	fTest(db.setInventory, 0)
+
+	db.setInventory = 0
+	err = CheckAllTheJournalContext(context.Background(), NewContextDB(db))
+	fTest(err, nil)
+	fTest(db.setInventory, 2)
+	fTest(kk.myInv[1001], Inventory{{1, 10, 100}, {2, 10, 100}, {3, 10, 100}})
+}
@@ -0,0 +1,267 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalCSVHeader is the first row of a journal CSV, every row after it is a line of an entry.
+var JournalCSVHeader = []string{"entry_ref", "date", "account", "cost_flow", "quantity", "amount"}
+
+// InventoryCSVHeader is the first row of an inventory CSV, every row after it is a cost layer of an account.
+var InventoryCSVHeader = []string{"account", "date", "quantity", "amount"}
+
+// CSVRowIssue describes a row of a CSV that can't be imported.
+type CSVRowIssue struct {
+	Row int   // the line of the row in the CSV, the header is the line 1
+	Err error // why the row can't be imported
+}
+
+// csvEntry is an entry of a CSV with the lines of its rows in the CSV.
+type csvEntry struct {
+	AccountingEntry
+	rows      []int
+	isDated   bool // a row of the entry has a valid date so all its rows should have it
+	isInvalid bool // a row of the entry is invalid so the entry is not complete
+}
+
+// ReadJournalCSV reads the entries of a CSV that starts with JournalCSVHeader.
+//
+// Parameters:
+//   - r: The CSV
+//   - dbCommand: The storage of the journal and the inventories, it is only read
+//
+// The rows with the same entry_ref are the lines of one entry in the order of the rows,
+// the entry_ref is its Reference and the entries are in the order of their first rows.
+// The date is RFC 3339 like 2024-03-01T10:00:00Z or a day like 2024-03-01 in UTC,
+// the cost_flow is a name like FIFO.
+//
+// The entries are checked and processed in their order like AddBatch does against a copy of the inventories,
+// so they can be posted with AddBatch after the last entry of the journal.
+//
+// Returns the entries, or the issues of the invalid rows sorted by row and an error if any row is invalid.
+func ReadJournalCSV(r io.Reader, dbCommand DB) ([]AccountingEntry, []CSVRowIssue, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(JournalCSVHeader)
+	err := readCSVHeader(reader, JournalCSVHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var issues []CSVRowIssue
+	var entries []*csvEntry
+	byRef := make(map[string]*csvEntry)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, nil, err
+		}
+		row, _ := reader.FieldPos(0)
+
+		ref := strings.TrimSpace(record[0])
+		entry, ok := byRef[ref]
+		if !ok {
+			entry = &csvEntry{}
+			byRef[ref] = entry
+			entries = append(entries, entry)
+		}
+
+		if err != nil {
+			err = newError(ErrInvalidCSVField, "the row has %v fields instead of %v", len(record), len(JournalCSVHeader))
+		} else {
+			err = parseJournalCSVRow(record, entry)
+		}
+		if err != nil {
+			issues = append(issues, CSVRowIssue{row, err})
+			entry.isInvalid = true
+			continue
+		}
+		entry.rows = append(entry.rows, row)
+	}
+
+	var valid []*csvEntry
+	var result []AccountingEntry
+	for _, entry := range entries {
+		if !entry.isInvalid {
+			valid = append(valid, entry)
+			result = append(result, entry.AccountingEntry)
+		}
+	}
+
+	batch, err := checkBatch(result, dbCommand)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, issue := range batch.issues {
+		entry := valid[issue.Index]
+		row := entry.rows[0]
+		var lineError *LineError
+		if errors.As(issue.Err, &lineError) {
+			row = entry.rows[lineError.LineIndex]
+		}
+		issues = append(issues, CSVRowIssue{row, issue.Err})
+	}
+
+	if len(issues) != 0 {
+		slices.SortStableFunc(issues, func(a, b CSVRowIssue) int { return a.Row - b.Row })
+		return nil, issues, newError(ErrCSVHasInvalidRows, "%v rows of the CSV are invalid", len(issues))
+	}
+	return result, nil, nil
+}
+
+// parseJournalCSVRow adds the line of the row to its entry.
+func parseJournalCSVRow(record []string, entry *csvEntry) error {
+	ref := strings.TrimSpace(record[0])
+	if ref == "" {
+		return fErrInvalidCSVField(JournalCSVHeader[0], record[0])
+	}
+
+	timeUnix, err := parseCSVDate(record[1])
+	if err != nil {
+		return err
+	}
+	if entry.isDated && timeUnix != entry.TimeUnix {
+		return newError(ErrInvalidCSVField, "the date %v is not the date of the rows before it with the entry_ref %v", strings.TrimSpace(record[1]), ref)
+	}
+	entry.TimeUnix = timeUnix
+	entry.isDated = true
+
+	accountID, err := strconv.ParseInt(strings.TrimSpace(record[2]), 10, 64)
+	if err != nil {
+		return fErrInvalidCSVField(JournalCSVHeader[2], record[2])
+	}
+
+	costFlowType, err := ParseCostFlowType(strings.ToUpper(strings.TrimSpace(record[3])))
+	if err != nil {
+		return fErrInvalidCSVField(JournalCSVHeader[3], record[3])
+	}
+
+	quantity, err := strconv.ParseFloat(strings.TrimSpace(record[4]), 64)
+	if err != nil {
+		return fErrInvalidCSVField(JournalCSVHeader[4], record[4])
+	}
+
+	amount, err := strconv.ParseFloat(strings.TrimSpace(record[5]), 64)
+	if err != nil {
+		return fErrInvalidCSVField(JournalCSVHeader[5], record[5])
+	}
+
+	entry.Reference = ref
+	entry.DoubleEntry = append(entry.DoubleEntry, SingleEntry{costFlowType, AccountID(accountID), Quantity(quantity), Amount(amount)})
+	return nil
+}
+
+// WriteJournalCSV writes the entries of the journal that the range selects as a CSV that ReadJournalCSV reads,
+// a row for every line of an entry with the ID of the entry as its entry_ref.
+func WriteJournalCSV(w io.Writer, dbCommand DB, r JournalRange) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(JournalCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for entry, err := range dbCommand.Journal(r) {
+		if err != nil {
+			return err
+		}
+
+		ref := strconv.FormatUint(uint64(entry.ID), 10)
+		for _, single := range entry.DoubleEntry {
+			costFlowType, err := FormatCostFlowType(single.CostFlowType)
+			if err != nil {
+				return err
+			}
+
+			err = writer.Write([]string{
+				ref,
+				formatCSVDate(entry.TimeUnix),
+				strconv.FormatInt(int64(single.AccountID), 10),
+				costFlowType,
+				formatCSVFloat(float64(single.Quantity)),
+				formatCSVFloat(float64(single.Amount)),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteInventoriesCSV writes the cost layers of the inventories as a CSV that starts with InventoryCSVHeader,
+// the accounts are sorted by their IDs and the layers of an account are in their order.
+func WriteInventoriesCSV(w io.Writer, IDAndInventory AccountIDAndInventory) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(InventoryCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, ID := range slices.Sorted(maps.Keys(IDAndInventory)) {
+		for _, record := range IDAndInventory[ID] {
+			err := writer.Write([]string{
+				strconv.FormatInt(int64(ID), 10),
+				formatCSVDate(record.TimeUnix),
+				formatCSVFloat(float64(record.Quantity)),
+				formatCSVFloat(float64(record.Amount)),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCSVHeader reads the first row and returns an error if it is not the header, the case and the spaces are ignored.
+func readCSVHeader(reader *csv.Reader, header []string) error {
+	record, err := reader.Read()
+	if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+		if err == io.EOF {
+			return newError(ErrInvalidCSVField, "the CSV is empty, its first row should be %v", strings.Join(header, ","))
+		}
+		return err
+	}
+
+	isHeader := len(record) == len(header)
+	for i := 0; isHeader && i < len(header); i++ {
+		isHeader = strings.EqualFold(strings.TrimSpace(record[i]), header[i])
+	}
+	if !isHeader {
+		return newError(ErrInvalidCSVField, "the first row of the CSV should be %v", strings.Join(header, ","))
+	}
+	return nil
+}
+
+func parseCSVDate(value string) (TimeUnix, error) {
+	value = strings.TrimSpace(value)
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+		if err != nil {
+			return 0, fErrInvalidCSVField(JournalCSVHeader[1], value)
+		}
+	}
+	return t.UnixMicro(), nil
+}
+
+func formatCSVDate(timeUnix TimeUnix) string {
+	return time.UnixMicro(timeUnix).UTC().Format(time.RFC3339Nano)
+}
+
+func formatCSVFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
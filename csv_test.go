@@ -0,0 +1,167 @@
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_WriteJournalCSV(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 1709287200000000, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 1709373600500000, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100.5}, {WAC, 2001, 100.5, 100.5}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	var b bytes.Buffer
+	fTest(WriteJournalCSV(&b, &kk, JournalRange{}), nil)
+	fTest(b.String(), `entry_ref,date,account,cost_flow,quantity,amount
+1,2024-03-01T10:00:00Z,-1001,INFLOW,0,1000
+1,2024-03-01T10:00:00Z,2001,INFLOW,1000,1000
+2,2024-03-02T10:00:00.5Z,1001,INFLOW,10,100.5
+2,2024-03-02T10:00:00.5Z,2001,WAC,100.5,100.5
+`)
+
+	// the exported journal is imported to an empty journal as the same entries
+	empty := myDB{myInv: make(AccountIDAndInventory)}
+	imported, issues, err := ReadJournalCSV(&b, &empty)
+	fTest(err, nil)
+	fTest(issues, nil)
+	fTest(imported, []AccountingEntry{
+		{TimeUnix: 1709287200000000, DoubleEntry: entries[0].DoubleEntry, Metadata: Metadata{Reference: "1"}},
+		{TimeUnix: 1709373600500000, DoubleEntry: entries[1].DoubleEntry, Metadata: Metadata{Reference: "2"}},
+	})
+
+	_, _, err = AddBatch(imported, &empty)
+	fTest(err, nil)
+	fTest(empty.myInv, kk.myInv)
+
+	b.Reset()
+	fTest(WriteInventoriesCSV(&b, kk.myInv), nil)
+	fTest(b.String(), `account,date,quantity,amount
+-1001,2024-03-01T10:00:00Z,0,1000
+1001,2024-03-02T10:00:00.5Z,10,100.5
+2001,2024-03-02T10:00:00.5Z,899.5,899.5
+`)
+}
+
+func Test_ReadJournalCSV(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+	_, err := AddToJournal(AccountingEntry{TimeUnix: 1709251200000000, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 0, 100}}}, &kk)
+	fTest(err, nil)
+
+	type input struct {
+		csv string
+	}
+	type output struct {
+		AccountingEntries []AccountingEntry
+		CSVRowIssues      []CSVRowIssue
+		err               error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				csv: ` Entry_Ref , Date ,Account,Cost_Flow,Quantity,Amount
+JE-2,2024-03-02,1001, fifo ,4,40
+JE-1,2024-03-02,2001,INFLOW,5,50
+JE-2,2024-03-02,2001,INFLOW,4,40
+JE-1,2024-03-02,-1001,inflow,0,50
+`,
+			},
+			output: output{
+				AccountingEntries: []AccountingEntry{
+					{TimeUnix: 1709337600000000, DoubleEntry: DoubleEntry{{FIFO, 1001, 4, 40}, {INFLOW, 2001, 4, 40}}, Metadata: Metadata{Reference: "JE-2"}},
+					{TimeUnix: 1709337600000000, DoubleEntry: DoubleEntry{{INFLOW, 2001, 5, 50}, {INFLOW, -1001, 0, 50}}, Metadata: Metadata{Reference: "JE-1"}},
+				},
+				CSVRowIssues: nil,
+				err:          nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				csv: `entry_ref,date,account,cost_flow,quantity,amount
+JE-1,2024-03-02,1001,FIFO,8,80
+JE-1,2024-03-02,2001,INFLOW,8,80
+JE-2,2024-03-02,1001,FIFO,8,80
+JE-2,2024-03-02,2001,INFLOW,8,80
+JE-3,2024-03-02,x,FIFO,1,1
+JE-3,2024-03-03,2001,INFLOW,1,1
+JE-4,2024-03-02,2001,AVERAGE,1,1
+JE-5,,2001,INFLOW,1,1
+,2024-03-02,2001,INFLOW,1,1
+JE-6,2024-03-02,2001,INFLOW,1
+JE-7,2024-02-01,2001,INFLOW,1,1
+JE-7,2024-02-01,-1001,INFLOW,0,1
+JE-8,2024-03-02,2001,INFLOW,1,1
+JE-8,2024-03-02,-1001,INFLOW,0,2
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				CSVRowIssues: []CSVRowIssue{
+					{4, fmt.Errorf("ErrInsufficientQuantityInInventory : You want to withdraw quantity = 8 but you do not have enough quantity because your total quantity = 2")},
+					{6, fmt.Errorf("ErrInvalidCSVField : the account \"x\" is invalid")},
+					{7, fmt.Errorf("ErrInvalidCSVField : the date 2024-03-03 is not the date of the rows before it with the entry_ref JE-3")},
+					{8, fmt.Errorf("ErrInvalidCSVField : the cost_flow \"AVERAGE\" is invalid")},
+					{9, fmt.Errorf("ErrInvalidCSVField : the date \"\" is invalid")},
+					{10, fmt.Errorf("ErrInvalidCSVField : the entry_ref \"\" is invalid")},
+					{11, fmt.Errorf("ErrInvalidCSVField : the row has 5 fields instead of 6")},
+					{12, fmt.Errorf("ErrTimeShouldBeBigger : time should be bigger")},
+					{14, fmt.Errorf("ErrDebitNotEqualCredit : debit not equal credit and debit = 1 , credit = 2 and debit-credit = -1")},
+				},
+				err: fmt.Errorf("ErrCSVHasInvalidRows : 9 rows of the CSV are invalid"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				csv: `ref,date,account,cost_flow,quantity,amount
+JE-1,2024-03-02,1001,FIFO,8,80
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				CSVRowIssues:      nil,
+				err:               fmt.Errorf("ErrInvalidCSVField : the first row of the CSV should be entry_ref,date,account,cost_flow,quantity,amount"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				csv: ``,
+			},
+			output: output{
+				AccountingEntries: nil,
+				CSVRowIssues:      nil,
+				err:               fmt.Errorf("ErrInvalidCSVField : the CSV is empty, its first row should be entry_ref,date,account,cost_flow,quantity,amount"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.AccountingEntries, output.CSVRowIssues, output.err = ReadJournalCSV(strings.NewReader(tt.input.csv), &kk)
+		output.err = goerrors.NormalizeTheError(output.err)
+		for i := range output.CSVRowIssues {
+			output.CSVRowIssues[i].Err = goerrors.NormalizeTheError(output.CSVRowIssues[i].Err)
+		}
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+	fTest(kk.myInv[1001], Inventory{{1709251200000000, 10, 100}})
+}
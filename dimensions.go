@@ -0,0 +1,74 @@
+package accounting
+
+import "maps"
+
+// Dimensions are the analytic dimensions of a line like the cost center, the project,
+// the department or the region, by the name of the dimension.
+type Dimensions map[string]string
+
+// RequiredDimensions are the names of the dimensions that every line of an account should have.
+type RequiredDimensions map[AccountID][]string
+
+// DimensionsDB is a DB that has the dimensions required for its accounts.
+// AddToJournal, Ledger, AddBatch, AddToJournalVersioned and InsertToJournal reject an entry that misses one of them,
+// except a closing entry because its lines are the balances of the accounts.
+type DimensionsDB interface {
+	DB
+	GetRequiredDimensions() (RequiredDimensions, error)
+}
+
+// GetLineDimensions returns the dimensions of the line of the account in the entry.
+// The line has the dimensions of the entry and its own dimensions, a dimension of the line wins over the same dimension of the entry.
+func GetLineDimensions(entry AccountingEntry, accountID AccountID) Dimensions {
+	dimensions := maps.Clone(entry.Dimensions)
+	if dimensions == nil {
+		dimensions = make(Dimensions)
+	}
+	maps.Copy(dimensions, entry.LinesMetadata[accountID].Dimensions)
+	return dimensions
+}
+
+// CheckRequiredDimensions returns an error for the first line of the entry that misses a dimension required for its account.
+func CheckRequiredDimensions(entry AccountingEntry, requiredDimensions RequiredDimensions) error {
+	for _, single := range entry.DoubleEntry {
+		required, ok := requiredDimensions[single.AccountID]
+		if !ok {
+			continue
+		}
+
+		dimensions := GetLineDimensions(entry, single.AccountID)
+		for _, name := range required {
+			if dimensions[name] == "" {
+				return newError(ErrMissingDimension, "the line of account ID %v should have the dimension %v", single.AccountID, name)
+			}
+		}
+	}
+	return nil
+}
+
+// getRequiredDimensions returns the required dimensions of the DB, or nil if it is not a DimensionsDB.
+func getRequiredDimensions(dbCommand DB) (RequiredDimensions, error) {
+	d, ok := dbCommand.(DimensionsDB)
+	if !ok {
+		return nil, nil
+	}
+	return d.GetRequiredDimensions()
+}
+
+// checkPostedDimensions checks that the entry has the dimensions required by the DB before it is posted.
+func checkPostedDimensions(entry AccountingEntry, requiredDimensions RequiredDimensions) error {
+	if entry.IsClosing {
+		return nil
+	}
+	return CheckRequiredDimensions(entry, requiredDimensions)
+}
+
+// matchDimensions reports whether the dimensions have all the values of the filter.
+func matchDimensions(filter Dimensions, dimensions Dimensions) bool {
+	for name, value := range filter {
+		if dimensions[name] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_GetLineDimensions(t *testing.T) {
+	entry := AccountingEntry{
+		DoubleEntry: DoubleEntry{{INFLOW, 3001, 5, 50}, {NONE, 2001, 50, 50}},
+		Metadata:    Metadata{Dimensions: Dimensions{"project": "apollo", "department": "sales"}},
+		LinesMetadata: map[AccountID]Metadata{
+			3001: {Dimensions: Dimensions{"department": "support", "cost center": "7"}},
+		},
+	}
+
+	fTest(GetLineDimensions(entry, 3001), Dimensions{"project": "apollo", "department": "support", "cost center": "7"})
+	fTest(GetLineDimensions(entry, 2001), Dimensions{"project": "apollo", "department": "sales"})
+	fTest(GetLineDimensions(AccountingEntry{}, 2001), Dimensions{})
+}
+
+func Test_CheckRequiredDimensions(t *testing.T) {
+	requiredDimensions := RequiredDimensions{
+		3001: {"project", "cost center"},
+	}
+
+	type input struct {
+		AccountingEntry AccountingEntry
+	}
+	type output struct {
+		err error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				AccountingEntry: AccountingEntry{
+					DoubleEntry:   DoubleEntry{{INFLOW, 3001, 5, 50}, {NONE, 2001, 50, 50}},
+					Metadata:      Metadata{Dimensions: Dimensions{"project": "apollo"}},
+					LinesMetadata: map[AccountID]Metadata{3001: {Dimensions: Dimensions{"cost center": "7"}}},
+				},
+			},
+			output: output{
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				AccountingEntry: AccountingEntry{
+					DoubleEntry: DoubleEntry{{INFLOW, 3001, 5, 50}, {NONE, 2001, 50, 50}},
+					Metadata:    Metadata{Dimensions: Dimensions{"project": "apollo"}},
+				},
+			},
+			output: output{
+				err: fmt.Errorf("ErrMissingDimension : the line of account ID 3001 should have the dimension cost center"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				AccountingEntry: AccountingEntry{
+					DoubleEntry: DoubleEntry{{INFLOW, 2002, 5, 50}, {NONE, 2001, 50, 50}},
+				},
+			},
+			output: output{
+				err: nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.err = CheckRequiredDimensions(tt.input.AccountingEntry, requiredDimensions)
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+// myDimensionsDB is myDB with required dimensions.
+type myDimensionsDB struct {
+	*myDB
+	requiredDimensions RequiredDimensions
+}
+
+func (d myDimensionsDB) GetRequiredDimensions() (RequiredDimensions, error) {
+	return d.requiredDimensions, nil
+}
+
+func Test_DimensionsDB(t *testing.T) {
+	kk := myDimensionsDB{&myDB{myInv: make(AccountIDAndInventory)}, RequiredDimensions{3001: {"project"}, -4001: {"project"}}}
+	missing := fmt.Errorf("ErrMissingDimension : the line of account ID 3001 should have the dimension project")
+
+	_, err := AddToJournal(AccountingEntry{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}}, kk)
+	fTest(err, nil)
+
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 50}, {WAC, 2001, 50, 50}}}, kk)
+	fTest(goerrors.NormalizeTheError(err), missing)
+
+	ID, err := AddToJournal(AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 50}, {WAC, 2001, 50, 50}}, Metadata: Metadata{Dimensions: Dimensions{"project": "A"}}}, kk)
+	fTest(err, nil)
+
+	// the reversal has the dimensions of the original
+	reversal, err := ReverseEntry(kk, ID, 3)
+	fTest(err, nil)
+	fTest(reversal.Dimensions, Dimensions{"project": "A"})
+
+	_, issues, err := AddBatch([]AccountingEntry{{TimeUnix: 4, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 50}, {WAC, 2001, 50, 50}}}}, kk)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrBatchHasInvalidEntries : 1 of the 1 entries of the batch are invalid"))
+	fTest(goerrors.NormalizeTheError(issues[0].Err), missing)
+
+	_, _, err = InsertToJournal(AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 50}, {WAC, 2001, 50, 50}}}, kk)
+	fTest(goerrors.NormalizeTheError(err), missing)
+
+	// a closing entry has no dimensions
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 4, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 50}, {WAC, 2001, 50, 50}}, Metadata: Metadata{Dimensions: Dimensions{"project": "B"}}}, kk)
+	fTest(err, nil)
+	closing, _, err := CloseFiscalYear(5, -3000, 3900, []AccountID{3001}, kk)
+	fTest(err, nil)
+
+	// and its reversal neither
+	_, err = ReverseEntry(kk, closing.ID, 6)
+	fTest(err, nil)
+}
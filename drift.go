@@ -0,0 +1,50 @@
+package accounting
+
+import (
+	"maps"
+	"slices"
+)
+
+// InventoryDrift is an account whose stored inventory is not the inventory that the journal gives.
+type InventoryDrift struct {
+	AccountID
+	Expected Inventory // the inventory that the replay of the journal gives
+	Stored   Inventory // the inventory that GetInventory returns
+}
+
+// FindInventoryDrift replays the journal like CheckAllTheJournal but it writes nothing,
+// it compares the inventory of every account of the journal with what GetInventory returns.
+// An empty inventory and no inventory are the same. Only the accounts that have lines in the journal are compared.
+//
+// Returns the accounts whose layers are different, sorted by account ID, or nil if there is no drift.
+// Use RepairInventoryDrift to write the expected inventories.
+func FindInventoryDrift(dbCommand DB) ([]InventoryDrift, error) {
+	IDAndInventory, err := replayTheJournal(dbCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []InventoryDrift
+	for _, ID := range slices.Sorted(maps.Keys(IDAndInventory)) {
+		stored, err := dbCommand.GetInventory(ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Equal(stored, IDAndInventory[ID]) {
+			drifts = append(drifts, InventoryDrift{ID, IDAndInventory[ID], stored})
+		}
+	}
+	return drifts, nil
+}
+
+// RepairInventoryDrift writes the expected inventory of every drift with SetInventory.
+func RepairInventoryDrift(drifts []InventoryDrift, dbCommand DB) error {
+	for _, drift := range drifts {
+		err := dbCommand.SetInventory(drift.AccountID, drift.Expected)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
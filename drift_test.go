@@ -0,0 +1,43 @@
+package accounting
+
+import (
+	"testing"
+)
+
+func Test_FindInventoryDrift(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+		{TimeUnix: 3, DoubleEntry: DoubleEntry{{FIFO, 1001, 10, 100}, {INFLOW, 3001, 10, 100}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	drifts, err := FindInventoryDrift(&kk)
+	fTest(err, nil)
+	fTest(drifts, nil)
+
+	kk.myInv[2001] = Inventory{{2, 900, 800}}
+	kk.myInv[1001] = Inventory{{3, 1, 10}}
+	delete(kk.myInv, 3001)
+	stored := AccountIDAndInventory{-1001: kk.myInv[-1001], 1001: kk.myInv[1001], 2001: kk.myInv[2001]}
+
+	drifts, err = FindInventoryDrift(&kk)
+	fTest(err, nil)
+	fTest(drifts, []InventoryDrift{
+		{1001, nil, Inventory{{3, 1, 10}}},
+		{2001, Inventory{{2, 900, 900}}, Inventory{{2, 900, 800}}},
+		{3001, Inventory{{3, 10, 100}}, Inventory{}},
+	})
+	fTest(kk.myInv, stored)
+
+	fTest(RepairInventoryDrift(drifts, &kk), nil)
+	drifts, err = FindInventoryDrift(&kk)
+	fTest(err, nil)
+	fTest(drifts, nil)
+}
@@ -0,0 +1,338 @@
+package accounting
+
+import (
+	"encoding/binary"
+	"maps"
+	"math"
+	"slices"
+)
+
+// EncodingVersion is the version of the binary and the JSON encodings that this package writes.
+//
+// A new version of the binary encoding can only add fields at the end of the entry, after the fields of the
+// versions before it, so a reader decodes the encodings of all the versions before its own and skips the fields
+// that it does not know at the end of the encodings of the versions after it. The lines, the layers and the metadata
+// are in the middle of the entry so they can't get new fields, that needs a new encoding that older readers can't read.
+// The JSON encoding can get new fields anywhere because the fields that a reader does not know are ignored.
+//
+// Version 2 added IsClosing.
+const EncodingVersion = 2
+
+// MarshalBinary returns the compact binary encoding of the entry: the EncodingVersion then all the fields of the entry,
+// with a nil and an empty slice or map encoded the same. It is not the encoding that HashEntry hashes,
+// that one is frozen and has other fields in another order, so the hash of this encoding is not the Hash of the entry.
+func (entry AccountingEntry) MarshalBinary() ([]byte, error) {
+	e := encoder{binary.AppendUvarint(nil, EncodingVersion)}
+	e.entry(entry)
+	return e.b, nil
+}
+
+// UnmarshalBinary decodes an entry made by MarshalBinary.
+// A nil and an empty slice or map are decoded as nil.
+func (entry *AccountingEntry) UnmarshalBinary(data []byte) error {
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+	decoded := d.entry()
+	err = d.end()
+	if err != nil {
+		return err
+	}
+	*entry = decoded
+	return nil
+}
+
+// MarshalBinary returns the compact binary encoding of the inventory: the EncodingVersion then the records.
+func (inv Inventory) MarshalBinary() ([]byte, error) {
+	e := encoder{binary.AppendUvarint(nil, EncodingVersion)}
+	e.inventory(inv)
+	return e.b, nil
+}
+
+// UnmarshalBinary decodes an inventory made by MarshalBinary, an empty inventory is decoded as nil.
+func (inv *Inventory) UnmarshalBinary(data []byte) error {
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+	decoded := d.inventory()
+	err = d.end()
+	if err != nil {
+		return err
+	}
+	*inv = decoded
+	return nil
+}
+
+// encoder appends values to a byte slice in a canonical form: the same values always give the same bytes.
+// The integers are varints, the floats are their IEEE 754 bits, the slices and strings start with their length
+// and the maps are sorted by their keys. A nil and an empty slice or map give the same bytes.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) uint(v uint64) {
+	e.b = binary.AppendUvarint(e.b, v)
+}
+
+func (e *encoder) int(v int64) {
+	e.b = binary.AppendVarint(e.b, v)
+}
+
+func (e *encoder) float(v float64) {
+	e.b = binary.BigEndian.AppendUint64(e.b, math.Float64bits(v))
+}
+
+func (e *encoder) bool(v bool) {
+	var b uint64
+	if v {
+		b = 1
+	}
+	e.uint(b)
+}
+
+func (e *encoder) string(v string) {
+	e.uint(uint64(len(v)))
+	e.b = append(e.b, v...)
+}
+
+func (e *encoder) hash(v Hash) {
+	e.b = append(e.b, v[:]...)
+}
+
+func (e *encoder) inventory(inv Inventory) {
+	e.uint(uint64(len(inv)))
+	for _, record := range inv {
+		e.int(record.TimeUnix)
+		e.float(float64(record.Quantity))
+		e.float(float64(record.Amount))
+	}
+}
+
+func (e *encoder) accountIDAndInventory(IDAndInventory AccountIDAndInventory) {
+	e.uint(uint64(len(IDAndInventory)))
+	for _, ID := range slices.Sorted(maps.Keys(IDAndInventory)) {
+		e.int(int64(ID))
+		e.inventory(IDAndInventory[ID])
+	}
+}
+
+func (e *encoder) metadata(m Metadata) {
+	e.string(m.Description)
+	e.string(m.Reference)
+	e.int(int64(m.Counterparty))
+	e.uint(uint64(len(m.Tags)))
+	for _, tag := range m.Tags {
+		e.string(tag)
+	}
+	e.uint(uint64(len(m.Dimensions)))
+	for _, name := range slices.Sorted(maps.Keys(m.Dimensions)) {
+		e.string(name)
+		e.string(m.Dimensions[name])
+	}
+}
+
+func (e *encoder) entry(entry AccountingEntry) {
+	e.uint(uint64(entry.ID))
+	e.int(entry.TimeUnix)
+	e.uint(uint64(entry.Sequence))
+	e.uint(uint64(len(entry.DoubleEntry)))
+	for _, single := range entry.DoubleEntry {
+		e.uint(uint64(single.CostFlowType))
+		e.int(int64(single.AccountID))
+		e.float(float64(single.Quantity))
+		e.float(float64(single.Amount))
+	}
+	e.uint(uint64(entry.ReversalOf))
+	e.uint(uint64(entry.ReversedBy))
+	e.accountIDAndInventory(entry.Layers)
+	e.metadata(entry.Metadata)
+	e.uint(uint64(len(entry.LinesMetadata)))
+	for _, ID := range slices.Sorted(maps.Keys(entry.LinesMetadata)) {
+		e.int(int64(ID))
+		e.metadata(entry.LinesMetadata[ID])
+	}
+	e.string(entry.IdempotencyKey)
+	e.hash(entry.PreviousHash)
+	e.hash(entry.Hash)
+	e.bool(entry.IsClosing) // version 2
+}
+
+// decoder reads the values that encoder appends. The first error is kept and every read after it returns zero values.
+type decoder struct {
+	b       []byte
+	version uint64
+	err     error
+}
+
+// newDecoder reads the version at the start of the data.
+func newDecoder(data []byte) (*decoder, error) {
+	d := &decoder{b: data}
+	d.version = d.uint()
+	if d.err == nil && d.version == 0 {
+		d.err = fErrInvalidEncoding("the version is zero")
+	}
+	return d, d.err
+}
+
+// end returns the first error, the bytes after the known fields are an error only if the version is not newer than EncodingVersion.
+func (d *decoder) end() error {
+	if d.err == nil && len(d.b) != 0 && d.version <= EncodingVersion {
+		d.err = fErrInvalidEncoding("there are bytes after the end")
+	}
+	return d.err
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = fErrInvalidEncoding("it ends before its last value")
+	}
+	d.b = nil
+}
+
+func (d *decoder) uint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) int() int64 {
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) float() float64 {
+	if len(d.b) < 8 {
+		d.fail()
+		return 0
+	}
+	v := math.Float64frombits(binary.BigEndian.Uint64(d.b))
+	d.b = d.b[8:]
+	return v
+}
+
+func (d *decoder) bool() bool {
+	v := d.uint()
+	if v > 1 {
+		if d.err == nil {
+			d.err = fErrInvalidEncoding("a boolean is not 0 or 1")
+		}
+		return false
+	}
+	return v == 1
+}
+
+// length reads the length of a slice, a map or a string, it can't be more than the bytes that are left
+// because every element takes at least one byte.
+func (d *decoder) length() int {
+	n := d.uint()
+	if n > uint64(len(d.b)) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	n := d.length()
+	v := string(d.b[:n])
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) hash() Hash {
+	var v Hash
+	if len(d.b) < len(v) {
+		d.fail()
+		return v
+	}
+	d.b = d.b[copy(v[:], d.b):]
+	return v
+}
+
+func (d *decoder) inventory() Inventory {
+	n := d.length()
+	if n == 0 {
+		return nil
+	}
+	inv := make(Inventory, n)
+	for i := range inv {
+		inv[i] = InventoryRecord{d.int(), Quantity(d.float()), Amount(d.float())}
+	}
+	return inv
+}
+
+func (d *decoder) accountIDAndInventory() AccountIDAndInventory {
+	n := d.length()
+	if n == 0 {
+		return nil
+	}
+	IDAndInventory := make(AccountIDAndInventory, n)
+	for range n {
+		ID := AccountID(d.int())
+		IDAndInventory[ID] = d.inventory()
+	}
+	return IDAndInventory
+}
+
+func (d *decoder) metadata() Metadata {
+	var m Metadata
+	m.Description = d.string()
+	m.Reference = d.string()
+	m.Counterparty = CounterpartyID(d.int())
+	if n := d.length(); n != 0 {
+		m.Tags = make([]string, n)
+		for i := range m.Tags {
+			m.Tags[i] = d.string()
+		}
+	}
+	if n := d.length(); n != 0 {
+		m.Dimensions = make(Dimensions, n)
+		for range n {
+			name := d.string()
+			m.Dimensions[name] = d.string()
+		}
+	}
+	return m
+}
+
+func (d *decoder) entry() AccountingEntry {
+	var entry AccountingEntry
+	entry.ID = EntryID(d.uint())
+	entry.TimeUnix = d.int()
+	entry.Sequence = Sequence(d.uint())
+	if n := d.length(); n != 0 {
+		entry.DoubleEntry = make(DoubleEntry, n)
+		for i := range entry.DoubleEntry {
+			entry.DoubleEntry[i] = SingleEntry{CostFlowType(d.uint()), AccountID(d.int()), Quantity(d.float()), Amount(d.float())}
+		}
+	}
+	entry.ReversalOf = EntryID(d.uint())
+	entry.ReversedBy = EntryID(d.uint())
+	entry.Layers = d.accountIDAndInventory()
+	entry.Metadata = d.metadata()
+	if n := d.length(); n != 0 {
+		entry.LinesMetadata = make(map[AccountID]Metadata, n)
+		for range n {
+			ID := AccountID(d.int())
+			entry.LinesMetadata[ID] = d.metadata()
+		}
+	}
+	entry.IdempotencyKey = d.string()
+	entry.PreviousHash = d.hash()
+	entry.Hash = d.hash()
+	if d.version >= 2 {
+		entry.IsClosing = d.bool()
+	}
+	return entry
+}
@@ -0,0 +1,186 @@
+package accounting
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+// fullEntry has every field of AccountingEntry so the round trip tests see them all.
+var fullEntry = AccountingEntry{
+	ID:          7,
+	TimeUnix:    -20,
+	Sequence:    2,
+	DoubleEntry: DoubleEntry{{LIFO, 1001, 5.5, 50.25}, {INFLOW, -4001, 5.5, 50.25}, {NONE, 2001, 0, 0}},
+	ReversalOf:  3,
+	ReversedBy:  9,
+	IsClosing:   true,
+	Layers:      AccountIDAndInventory{1001: Inventory{{1, 2, 20}, {2, 3.5, 30.25}}},
+	Metadata: Metadata{
+		Description:  "sale",
+		Reference:    "INV-1",
+		Counterparty: 12,
+		Tags:         []string{"b", "a"},
+		Dimensions:   Dimensions{"project": "x", "region": "north"},
+	},
+	LinesMetadata:  map[AccountID]Metadata{-4001: {Description: "revenue", Tags: []string{"sales"}}},
+	IdempotencyKey: "key-1",
+	PreviousHash:   Hash{1, 2, 3},
+	Hash:           Hash{31: 255},
+}
+
+func Test_AccountingEntry_MarshalBinary(t *testing.T) {
+	b, err := fullEntry.MarshalBinary()
+	fTest(err, nil)
+	var decoded AccountingEntry
+	fTest(decoded.UnmarshalBinary(b), nil)
+	fTest(decoded, fullEntry)
+
+	// the encoding of a version should never change, version 2 only adds IsClosing at the end of version 1
+	entry := AccountingEntry{ID: 1, TimeUnix: 10, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, -4001, 5, 50}}, Metadata: Metadata{Description: "sale"}}
+	version1 := "0114000202d20f4014000000000000404900000000000000c13e401400000000000040490000000000000000000473616c65" + strings.Repeat("00", 6+2*len(Hash{}))
+	b, err = entry.MarshalBinary()
+	fTest(err, nil)
+	fTest(hex.EncodeToString(b), "02"+version1+"00")
+
+	b, err = hex.DecodeString("01" + version1)
+	fTest(err, nil)
+	decoded = AccountingEntry{}
+	fTest(decoded.UnmarshalBinary(b), nil)
+	fTest(decoded, entry)
+
+	inv, err := Inventory{{10, 5, 50}}.MarshalBinary()
+	fTest(err, nil)
+	fTest(hex.EncodeToString(inv), "02011440140000000000004049000000000000")
+}
+
+func Test_AccountingEntry_UnmarshalBinary(t *testing.T) {
+	valid, err := fullEntry.MarshalBinary()
+	fTest(err, nil)
+	newer := append([]byte{EncodingVersion + 1}, valid[1:]...)
+
+	type input struct {
+		data []byte
+	}
+	type output struct {
+		AccountingEntry AccountingEntry
+		err             error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: append(newer, 1, 2, 3),
+			},
+			output: output{
+				AccountingEntry: fullEntry,
+				err:             nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: append(valid, 0),
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because there are bytes after the end"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: valid[:len(valid)-1],
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because it ends before its last value"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: append([]byte{0}, valid[1:]...),
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because the version is zero"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: nil,
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because it ends before its last value"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: []byte{1, 1, 0, 0, 0xff, 0xff, 0xff, 0xff, 0x0f},
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because it ends before its last value"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.err = goerrors.NormalizeTheError(output.AccountingEntry.UnmarshalBinary(tt.input.data))
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_Inventory_UnmarshalBinary(t *testing.T) {
+	for _, inv := range []Inventory{nil, {{1, 2, 20}}, {{1, 2, 20}, {-5, 0.1, 0.3}}} {
+		b, err := inv.MarshalBinary()
+		fTest(err, nil)
+		var decoded Inventory
+		fTest(decoded.UnmarshalBinary(b), nil)
+		fTest(decoded, inv)
+	}
+
+	var decoded Inventory
+	err := decoded.UnmarshalBinary([]byte{1, 1, 20})
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because it ends before its last value"))
+}
+
+func Fuzz_AccountingEntry_UnmarshalBinary(f *testing.F) {
+	b, _ := fullEntry.MarshalBinary()
+	f.Add(b)
+	b, _ = AccountingEntry{}.MarshalBinary()
+	f.Add(b)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var entry AccountingEntry
+		if entry.UnmarshalBinary(data) != nil {
+			return
+		}
+
+		// every entry that decodes should encode and decode again to itself
+		b, err := entry.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again AccountingEntry
+		err = again.UnmarshalBinary(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if HashEntry(again) != HashEntry(entry) || again.Hash != entry.Hash || again.ReversedBy != entry.ReversedBy {
+			t.Fatalf("the entry %+v is %+v after the round trip", entry, again)
+		}
+	})
+}
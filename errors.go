@@ -0,0 +1,128 @@
+package accounting
+
+import (
+	"errors"
+
+	"github.com/HashemJaafar7/goerrors"
+)
+
+// The kinds of the errors of this package, matched by errors.Is.
+// Every Error has one kind by its Name, like ErrInsufficientQuantityInInventory is ErrInsufficientInventory,
+// so an API layer can map the kinds to its own codes without matching the messages.
+var (
+	ErrInvalidEntry          = errors.New("ErrInvalidEntry")          // the entry itself is wrong and should be fixed
+	ErrInsufficientInventory = errors.New("ErrInsufficientInventory") // the inventory of an account can't give what the entry takes out
+	ErrNotFound              = errors.New("ErrNotFound")              // the entry that is asked for is not in the journal
+	ErrConflict              = errors.New("ErrConflict")              // the entry is valid but it conflicts with the journal
+)
+
+// ErrInvalidLine is matched by errors.Is for every LineError.
+var ErrInvalidLine = errors.New("ErrInvalidLine")
+
+// kinds is the kind of every error name.
+var kinds = map[string]error{
+	ErrQuantityAndAmountAreZero:                                  ErrInvalidEntry,
+	ErrTimeShouldBeBigger:                                        ErrInvalidEntry,
+	ErrDuplicateAccountInEntry:                                   ErrInvalidEntry,
+	ErrDebitNotEqualCredit:                                       ErrInvalidEntry,
+	ErrInventoryNotFoundForAccountID:                             ErrInsufficientInventory,
+	ErrQuantityAndAmountShouldBothBeDebitOrCredit:                ErrInvalidEntry,
+	ErrTheCostFlowTypeIsWrong:                                    ErrInvalidEntry,
+	ErrInventoryIsEmpty:                                          ErrInsufficientInventory,
+	ErrInsufficientQuantityInInventory:                           ErrInsufficientInventory,
+	ErrAmountMismatch:                                            ErrInvalidEntry,
+	ErrInsufficientAmountInInventory:                             ErrInsufficientInventory,
+	ErrTheQuantityAndAmountShouldBeBothPositive:                  ErrInvalidEntry,
+	ErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero: ErrInvalidEntry,
+	ErrNoBalanceToClose:                                          ErrInvalidEntry,
+	ErrEntryNotFound:                                             ErrNotFound,
+	ErrEntryIsAlreadyReversed:                                    ErrConflict,
+	ErrLayersMismatch:                                            ErrInvalidEntry,
+	ErrLayerNotFoundInInventory:                                  ErrInsufficientInventory,
+	ErrInsertInvalidatesLaterEntries:                             ErrConflict,
+	ErrMetadataWithoutLine:                                       ErrInvalidEntry,
+	ErrLayersWithoutLine:                                         ErrInvalidEntry,
+	ErrLayerAfterEntry:                                           ErrInvalidEntry,
+	ErrLayersNotByCostFlowType:                                   ErrInvalidEntry,
+	ErrMissingDimension:                                          ErrInvalidEntry,
+	ErrIdempotencyKeyReused:                                      ErrConflict,
+	ErrBatchHasInvalidEntries:                                    ErrInvalidEntry,
+	ErrVersionConflict:                                           ErrConflict,
+	ErrCheckpointMismatch:                                        ErrConflict,
+	ErrHashChainBroken:                                           ErrConflict,
+	ErrInvalidEncoding:                                           ErrInvalidEntry,
+	ErrInvalidCSVField:                                           ErrInvalidEntry,
+	ErrCSVHasInvalidRows:                                         ErrInvalidEntry,
+	ErrInvalidPlainText:                                          ErrInvalidEntry,
+	ErrPlainTextMapping:                                          ErrInvalidEntry,
+	ErrInvalidBankStatement:                                      ErrInvalidEntry,
+	ErrUnsupportedByDB:                                           ErrConflict,
+}
+
+// Error is an error of this package with its name, one of the Err constants.
+// errors.Is matches it with its kind, like ErrInvalidEntry, and with an Error that has the same name:
+//
+//	errors.Is(err, &accounting.Error{Name: accounting.ErrDebitNotEqualCredit})
+type Error struct {
+	Name string
+	Err  error // the error made by goerrors.Errorf
+}
+
+// newError is goerrors.Errorf that returns an Error.
+func newError(name string, format string, args ...any) error {
+	return &Error{name, goerrors.Errorf(name, format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	if t, ok := target.(*Error); ok {
+		return t.Name == e.Name
+	}
+	return target == kinds[e.Name]
+}
+
+// InsufficientInventoryError is the error of a line that takes out more quantity or amount than its inventory has.
+// Only the quantity or only the amount is set, the one that is not enough.
+type InsufficientInventoryError struct {
+	AccountID         // set when the error is returned by CheckAndProcessDoubleEntry
+	RequestedQuantity Quantity
+	AvailableQuantity Quantity
+	RequestedAmount   Amount
+	AvailableAmount   Amount
+	Err               error // the Error with the name ErrInsufficientQuantityInInventory or ErrInsufficientAmountInInventory
+}
+
+func (e *InsufficientInventoryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InsufficientInventoryError) Unwrap() error {
+	return e.Err
+}
+
+// LineError is the error of a line of an entry, returned by CheckAndProcessDoubleEntry.
+// Use errors.As to get the line and errors.Unwrap to get the error of the line.
+type LineError struct {
+	LineIndex int // the index of the line in the DoubleEntry
+	SingleEntry
+	Err error
+}
+
+func (e *LineError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+func (e *LineError) Is(target error) bool {
+	return target == ErrInvalidLine
+}
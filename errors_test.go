@@ -0,0 +1,72 @@
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+)
+
+func Test_LineError(t *testing.T) {
+	entry := AccountingEntry{
+		TimeUnix:    2,
+		DoubleEntry: DoubleEntry{{INFLOW, 3001, 20, 200}, {FIFO, 1001, 20, 200}},
+	}
+	_, err := CheckAndProcessDoubleEntry(1, entry, AccountIDAndInventory{1001: Inventory{{1, 10, 100}}})
+
+	var lineError *LineError
+	fTest(errors.As(err, &lineError), true)
+	fTest(lineError.LineIndex, 1)
+	fTest(lineError.SingleEntry, SingleEntry{FIFO, 1001, 20, 200})
+	fTest(errors.Is(err, ErrInvalidLine), true)
+	fTest(goerrors.NormalizeTheError(errors.Unwrap(err)), fmt.Errorf("ErrInsufficientQuantityInInventory : You want to withdraw quantity = 20 but you do not have enough quantity because your total quantity = 10"))
+
+	_, err = CheckAndProcessDoubleEntry(1, AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 1, 1}}}, AccountIDAndInventory{})
+	fTest(errors.Is(err, ErrInvalidLine), false)
+
+	_, err = checkAndProcessCostOutFlow(2, SingleEntry{INFLOW, 1001, 5, 50}, Inventory{{1, 10, 100}})
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrTheCostFlowTypeIsWrong : the cost flow type 0 can't take out of the inventory of account ID 1001"))
+}
+
+func Test_Error(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	_, err := AddToJournal(AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 10, 100}}}, &kk)
+	fTest(err, nil)
+
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, 3001, 5, 80}, {FIFO, 1001, 5, 80}}}, &kk)
+	fTest(errors.Is(err, ErrInvalidEntry), true)
+	fTest(errors.Is(err, &Error{Name: ErrAmountMismatch}), true)
+	fTest(errors.Is(err, &Error{Name: ErrDebitNotEqualCredit}), false)
+	fTest(errors.Is(err, ErrInsufficientInventory), false)
+
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, 3001, 15, 150}, {FIFO, 1001, 15, 150}}}, &kk)
+	fTest(errors.Is(err, ErrInsufficientInventory), true)
+	fTest(errors.Is(err, &Error{Name: ErrInsufficientQuantityInInventory}), true)
+	var insufficient *InsufficientInventoryError
+	fTest(errors.As(err, &insufficient), true)
+	fTest(*insufficient, InsufficientInventoryError{
+		AccountID:         1001,
+		RequestedQuantity: 15,
+		AvailableQuantity: 10,
+		Err:               insufficient.Err,
+	})
+
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 10, 100}}}, &kk)
+	fTest(errors.Is(err, ErrInvalidEntry), true)
+	fTest(errors.Is(err, ErrInvalidLine), false)
+
+	_, err = ReverseEntry(&kk, 9, 4)
+	fTest(errors.Is(err, ErrNotFound), true)
+
+	_, err = ReverseEntry(&kk, 1, 4)
+	fTest(err, nil)
+	_, err = ReverseEntry(&kk, 1, 4)
+	fTest(errors.Is(err, ErrConflict), true)
+
+	var e *Error
+	fTest(errors.As(err, &e), true)
+	fTest(e.Name, ErrEntryIsAlreadyReversed)
+}
@@ -2,6 +2,8 @@ package accounting_test
 
 import (
 	"fmt"
+	"iter"
+	"slices"
 	"time"
 
 	"github.com/HashemJaafar7/accounting"
@@ -9,10 +11,10 @@ import (
 
 // Set up required helper functions
 type myDB struct {
-	myInv         accounting.AccountIDAndInventory
-	myEntries     []accounting.AccountingEntry
-	lastEntryTime accounting.TimeUnix
-	i             int
+	myInv       accounting.AccountIDAndInventory
+	myEntries   []accounting.AccountingEntry
+	lastEntry   accounting.AccountingEntry
+	lastEntryID accounting.EntryID
 }
 
 func (s *myDB) GetInventory(key accounting.AccountID) (accounting.Inventory, error) {
@@ -26,22 +28,61 @@ func (s *myDB) SetInventory(key accounting.AccountID, value accounting.Inventory
 	s.myInv[key] = value
 	return nil
 }
-func (s *myDB) GetLastEntryTime() (accounting.TimeUnix, error) {
-	return s.lastEntryTime, nil
+func (s *myDB) GetLastEntry() (accounting.AccountingEntry, error) {
+	return s.lastEntry, nil
+}
+func (s *myDB) GetLastEntryID() (accounting.EntryID, error) {
+	return s.lastEntryID, nil
+}
+func (s *myDB) GetEntry(ID accounting.EntryID) (accounting.AccountingEntry, bool, error) {
+	for _, e := range s.myEntries {
+		if e.ID == ID {
+			return e, true, nil
+		}
+	}
+	return accounting.AccountingEntry{}, false, nil
 }
 func (s *myDB) SetEntry(value accounting.AccountingEntry) error {
-	s.lastEntryTime = value.TimeUnix
+	s.lastEntry = value
+	s.lastEntryID = value.ID
 	s.myEntries = append(s.myEntries, value)
 	return nil
 }
-func (s *myDB) IterOnJournal() (accounting.AccountingEntry, bool, error) {
-	if len(s.myEntries) == s.i {
-		return accounting.AccountingEntry{}, false, nil
+func (s *myDB) InsertEntry(value accounting.AccountingEntry) error {
+	i := slices.IndexFunc(s.myEntries, func(e accounting.AccountingEntry) bool {
+		return e.TimeUnix > value.TimeUnix
+	})
+	if i == -1 {
+		return s.SetEntry(value)
 	}
-
-	a := s.myEntries[s.i]
-	s.i++
-	return a, true, nil
+	s.myEntries = slices.Insert(s.myEntries, i, value)
+	s.lastEntryID = value.ID
+	return nil
+}
+func (s *myDB) Journal(r accounting.JournalRange) iter.Seq2[accounting.AccountingEntry, error] {
+	return func(yield func(accounting.AccountingEntry, error) bool) {
+		for _, e := range s.myEntries {
+			if r.Match(e) && !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+func (s *myDB) SetEntryReversedBy(entry accounting.EntryID, reversal accounting.EntryID) error {
+	for i := range s.myEntries {
+		if s.myEntries[i].ID == entry {
+			s.myEntries[i].ReversedBy = reversal
+		}
+	}
+	return nil
+}
+func (s *myDB) GetEntryByIdempotencyKey(key string) (accounting.AccountingEntry, bool, error) {
+	for _, e := range s.myEntries {
+		if e.IdempotencyKey == key {
+			return e, true, nil
+		}
+	}
+	return accounting.AccountingEntry{}, false, nil
 }
 
 var kk myDB
@@ -78,7 +119,7 @@ func Example_purchaseInventory() {
 		}
 
 		// Add the entry to the journal
-		err := accounting.AddToJournal(entry, &kk)
+		_, err := accounting.AddToJournal(entry, &kk)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			return
@@ -107,7 +148,7 @@ func Example_purchaseInventory() {
 			},
 		}
 
-		err := accounting.AddToJournal(entry, &kk)
+		_, err := accounting.AddToJournal(entry, &kk)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			return
@@ -149,7 +190,7 @@ func Example_purchaseInventory() {
 			},
 		}
 
-		err := accounting.AddToJournal(entry, &kk)
+		_, err := accounting.AddToJournal(entry, &kk)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			return
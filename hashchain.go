@@ -0,0 +1,173 @@
+package accounting
+
+import (
+	"crypto/sha256"
+	"maps"
+	"slices"
+)
+
+// HashEntry returns the hash of the entry with its PreviousHash in the encoding of hashEncodingV1.
+// The Hash of the entry and its ReversedBy are not in the hash because the first is the result
+// and the second is set after the entry is posted.
+func HashEntry(entry AccountingEntry) Hash {
+	return sha256.Sum256(hashEncodingV1(entry))
+}
+
+// hashEncodingV1 is the canonical encoding of the entry that HashEntry hashes. It is frozen because the journal keeps
+// the hashes that it gives, so it does not follow the binary format of MarshalBinary. A field that is added to
+// AccountingEntry is not hashed until there is a hashEncodingV2.
+func hashEncodingV1(entry AccountingEntry) []byte {
+	metadata := func(e *encoder, m Metadata) {
+		e.string(m.Description)
+		e.string(m.Reference)
+		e.int(int64(m.Counterparty))
+		e.uint(uint64(len(m.Tags)))
+		for _, tag := range m.Tags {
+			e.string(tag)
+		}
+		e.uint(uint64(len(m.Dimensions)))
+		for _, name := range slices.Sorted(maps.Keys(m.Dimensions)) {
+			e.string(name)
+			e.string(m.Dimensions[name])
+		}
+	}
+
+	var e encoder
+	e.uint(1) // the version of the hash encoding
+	e.uint(uint64(entry.ID))
+	e.int(entry.TimeUnix)
+	e.uint(uint64(entry.Sequence))
+	e.uint(uint64(len(entry.DoubleEntry)))
+	for _, single := range entry.DoubleEntry {
+		e.uint(uint64(single.CostFlowType))
+		e.int(int64(single.AccountID))
+		e.float(float64(single.Quantity))
+		e.float(float64(single.Amount))
+	}
+	e.uint(uint64(entry.ReversalOf))
+	e.bool(entry.IsClosing)
+	e.uint(uint64(len(entry.Layers)))
+	for _, ID := range slices.Sorted(maps.Keys(entry.Layers)) {
+		e.int(int64(ID))
+		e.uint(uint64(len(entry.Layers[ID])))
+		for _, record := range entry.Layers[ID] {
+			e.int(record.TimeUnix)
+			e.float(float64(record.Quantity))
+			e.float(float64(record.Amount))
+		}
+	}
+	metadata(&e, entry.Metadata)
+	e.uint(uint64(len(entry.LinesMetadata)))
+	for _, ID := range slices.Sorted(maps.Keys(entry.LinesMetadata)) {
+		e.int(int64(ID))
+		metadata(&e, entry.LinesMetadata[ID])
+	}
+	e.string(entry.IdempotencyKey)
+	e.hash(entry.PreviousHash)
+	return e.b
+}
+
+// chainEntry gives the entry the next ID and chains it to the last posted entry,
+// the entry with the biggest ID that is not always the last entry in time because of InsertToJournal.
+func chainEntry(entry AccountingEntry, dbCommand DB) (AccountingEntry, error) {
+	lastID, lastHash, err := getLastPosted(dbCommand)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+	return chainAfter(entry, lastID, lastHash), nil
+}
+
+// getLastPosted returns the ID and the Hash of the last posted entry.
+func getLastPosted(dbCommand DB) (EntryID, Hash, error) {
+	lastID, err := dbCommand.GetLastEntryID()
+	if err != nil || lastID == 0 {
+		return 0, Hash{}, err
+	}
+
+	lastPosted, _, err := dbCommand.GetEntry(lastID)
+	if err != nil {
+		return 0, Hash{}, err
+	}
+	return lastID, lastPosted.Hash, nil
+}
+
+// chainAfter returns the entry with the ID after lastID, chained to lastHash.
+func chainAfter(entry AccountingEntry, lastID EntryID, lastHash Hash) AccountingEntry {
+	entry.ID = lastID + 1
+	entry.PreviousHash = lastHash
+	entry.Hash = HashEntry(entry)
+	return entry
+}
+
+// hashChain checks the hash chain of the entries of the journal that are added to it in any order.
+type hashChain map[EntryID]chainLink
+
+type chainLink struct {
+	PreviousHash Hash
+	Hash         Hash
+	ComputedHash Hash // the hash of the content of the entry as it is now
+}
+
+func (c hashChain) add(entry AccountingEntry) {
+	c[entry.ID] = chainLink{entry.PreviousHash, entry.Hash, HashEntry(entry)}
+}
+
+// check returns an Error with the name ErrHashChainBroken for the entry with the smallest ID
+// whose content is not its Hash or whose PreviousHash is not the Hash of the entry posted before it.
+// The entries posted before the hash chain existed have no hashes, they are only allowed before all the hashed entries.
+func (c hashChain) check() error {
+	var previous Hash
+	for _, ID := range slices.Sorted(maps.Keys(c)) {
+		err := c[ID].check(ID, previous)
+		if err != nil {
+			return err
+		}
+		previous = c[ID].Hash
+	}
+	return nil
+}
+
+// checkJournalOrder returns an Error with the name ErrHashChainBroken if the entry is not after the entry before it
+// in the journal, with the time and the Sequence, by its time or by the next Sequence of the same time.
+// The first entry of the journal has the Sequence zero.
+func checkJournalOrder(isFirst bool, timeUnix TimeUnix, sequence Sequence, entry AccountingEntry) error {
+	if isFirst && entry.Sequence == 0 || !isFirst && (entry.TimeUnix > timeUnix || entry.TimeUnix == timeUnix && entry.Sequence == sequence+1) {
+		return nil
+	}
+	return fErrHashChainBroken(entry.ID)
+}
+
+// checkAfter is check for the entries of a part of the journal: the entry posted before an entry that is not
+// in the chain, like the last entry of a checkpoint, is read with GetEntry.
+func (c hashChain) checkAfter(dbCommand DB) error {
+	for _, ID := range slices.Sorted(maps.Keys(c)) {
+		previous, ok := c[ID-1]
+		if !ok && ID > 1 {
+			entry, isFound, err := dbCommand.GetEntry(ID - 1)
+			if err != nil {
+				return err
+			}
+			if !isFound {
+				return fErrHashChainBroken(ID)
+			}
+			previous.Hash = entry.Hash
+		}
+
+		err := c[ID].check(ID, previous.Hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns an Error with the name ErrHashChainBroken if the link of the entry with ID is not chained to the previous hash.
+func (link chainLink) check(ID EntryID, previous Hash) error {
+	if link.Hash == (Hash{}) && link.PreviousHash == (Hash{}) && previous == (Hash{}) {
+		return nil
+	}
+	if link.Hash != link.ComputedHash || link.PreviousHash != previous {
+		return fErrHashChainBroken(ID)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package accounting
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_HashChain(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 10, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 20, DoubleEntry: DoubleEntry{{INFLOW, -1002, 0, 50}, {INFLOW, 2002, 50, 50}}},
+		{TimeUnix: 30, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+	_, _, err := InsertToJournal(AccountingEntry{TimeUnix: 15, DoubleEntry: DoubleEntry{{INFLOW, -1003, 0, 5}, {INFLOW, 2003, 5, 5}}}, &kk)
+	fTest(err, nil)
+
+	// the entries in the order of their IDs, the inserted entry is the last one
+	posted := slices.Clone(kk.myEntries)
+	slices.SortFunc(posted, func(a, b AccountingEntry) int { return int(a.ID) - int(b.ID) })
+	for i, entry := range posted {
+		fTest(entry.Hash, HashEntry(entry))
+		if i > 0 {
+			fTest(entry.PreviousHash, posted[i-1].Hash)
+		}
+	}
+
+	// rechain hashes the entries again from the entry with the ID from, like they were posted that way
+	rechain := func(entries []AccountingEntry, from EntryID) {
+		var lastHash Hash
+		for i, entry := range entries {
+			if entry.ID >= from {
+				entries[i] = chainAfter(entry, entry.ID-1, lastHash)
+			}
+			lastHash = entries[i].Hash
+		}
+	}
+
+	type input struct {
+		change func(entries []AccountingEntry) []AccountingEntry
+	}
+	type output struct {
+		err error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				change: func(entries []AccountingEntry) []AccountingEntry { return entries },
+			},
+			output: output{
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				change: func(entries []AccountingEntry) []AccountingEntry {
+					entries[1].Metadata.Description = "changed"
+					return entries
+				},
+			},
+			output: output{
+				err: fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 2, its content, its order or the entry posted before it was changed"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				change: func(entries []AccountingEntry) []AccountingEntry {
+					entries[1].Metadata.Description = "changed"
+					entries[1].Hash = HashEntry(entries[1])
+					return entries
+				},
+			},
+			output: output{
+				err: fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 3, its content, its order or the entry posted before it was changed"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				change: func(entries []AccountingEntry) []AccountingEntry {
+					entries[1].ID, entries[2].ID = entries[2].ID, entries[1].ID
+					return entries
+				},
+			},
+			output: output{
+				err: fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 2, its content, its order or the entry posted before it was changed"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				change: func(entries []AccountingEntry) []AccountingEntry {
+					return slices.Delete(entries, 1, 2)
+				},
+			},
+			output: output{
+				err: fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 3, its content, its order or the entry posted before it was changed"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				change: func(entries []AccountingEntry) []AccountingEntry {
+					// the first two entries were posted before the hash chain existed
+					entries[0].Hash = Hash{}
+					entries[1].PreviousHash, entries[1].Hash = Hash{}, Hash{}
+					rechain(entries, 3)
+					return entries
+				},
+			},
+			output: output{
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				change: func(entries []AccountingEntry) []AccountingEntry {
+					entries[2].PreviousHash, entries[2].Hash = Hash{}, Hash{}
+					rechain(entries, 4)
+					return entries
+				},
+			},
+			output: output{
+				err: fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 3, its content, its order or the entry posted before it was changed"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		changed := tt.input.change(slices.Clone(posted))
+		slices.SortFunc(changed, func(a, b AccountingEntry) int { return int(a.TimeUnix - b.TimeUnix) })
+		db := myDB{myInv: make(AccountIDAndInventory), myEntries: changed}
+
+		var output output
+		output.err = goerrors.NormalizeTheError(CheckAllTheJournal(&db))
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_HashChain_order(t *testing.T) {
+	type input struct {
+		entries []AccountingEntry
+	}
+	type output struct {
+		err error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				entries: []AccountingEntry{
+					{TimeUnix: 10, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+					{TimeUnix: 10, DoubleEntry: DoubleEntry{{INFLOW, -1002, 0, 50}, {INFLOW, 2002, 50, 50}}},
+				},
+			},
+			output: output{
+				err: fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 2, its content, its order or the entry posted before it was changed"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				entries: []AccountingEntry{
+					{TimeUnix: 10, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+					{TimeUnix: 10, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+				},
+			},
+			output: output{
+				err: fmt.Errorf("ErrHashChainBroken : the hash chain is broken at the entry with ID 2, its content, its order or the entry posted before it was changed"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		kk := myDB{myInv: make(AccountIDAndInventory)}
+		for _, entry := range tt.input.entries {
+			_, err := AddToJournal(entry, &kk)
+			fTest(err, nil)
+		}
+
+		// the stored order of the entries of the same time is swapped
+		slices.Reverse(kk.myEntries)
+
+		var output output
+		output.err = goerrors.NormalizeTheError(CheckAllTheJournal(&kk))
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_HashEntry(t *testing.T) {
+	// the hash of version 1 should never change, even when the binary encoding or AccountingEntry changes
+	entry := AccountingEntry{ID: 1, TimeUnix: 10, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, -4001, 5, 50}}, Metadata: Metadata{Description: "sale"}}
+	hash := HashEntry(entry)
+	fTest(hex.EncodeToString(hash[:]), "6e477cb4c501e9a975f3a7e354f9685bbe75738f7a7a13829506b6b34f375af1")
+
+	// the fields that are set after the entry is posted are not in the hash
+	entry.ReversedBy = 5
+	entry.Hash = hash
+	fTest(HashEntry(entry), hash)
+}
@@ -0,0 +1,515 @@
+package accounting
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WriteHledger writes the entries of the journal that the range selects in the plain-text journal format
+// of hledger and ledger-cli.
+//
+// Every entry is a transaction with its date, its Reference as its code and its Description,
+// and every line is a posting of its account with a positive number for a debit and a negative number for a credit.
+// A line of a quantity of a commodity is written as one posting for every cost layer that it adds to or takes
+// out of the inventory, with the cost of the layer as the lot cost, its acquisition date as the lot date, its time
+// as the lot note if it is not the start of a day in UTC, and its amount as the total price, so both tools balance
+// the transaction in the currency:
+//
+//	2024-03-03 (INV-1) sale
+//	    ; time: 2024-03-03T10:00:00Z
+//	    inventory:widgets  -4 WIDGET {10 USD} [2024-03-01] @@ 40 USD
+//	    inventory:widgets  -2 WIDGET {12 USD} [2024-03-02] @@ 24 USD  ; cost_flow: LIFO
+//	    expenses:cost      64 USD
+//
+// The time is written as a time tag if it is not the start of a day in UTC, and the cost flow type as
+// a cost_flow tag if it is not the one that ReadHledger gives to a posting without it.
+// A WAC line is written as one lot without a date.
+//
+// The journal is read from its first entry to know the layers in the inventories.
+func WriteHledger(w io.Writer, dbCommand DB, r JournalRange, config PlainTextConfig) error {
+	writer := bufio.NewWriter(w)
+	var lastEntry AccountingEntry
+	isFirst := true
+	IDAndInventory := make(AccountIDAndInventory)
+	for entry, err := range dbCommand.Journal(JournalRange{To: r.To}) {
+		if err != nil {
+			return err
+		}
+
+		if r.Match(entry) {
+			if !isFirst {
+				writer.WriteString("\n")
+			}
+			isFirst = false
+
+			err = writeHledgerTransaction(writer, entry, IDAndInventory, config)
+			if err != nil {
+				return err
+			}
+		}
+
+		IDAndInventory, err = CheckAndProcessDoubleEntry(lastEntry.TimeUnix, entry, IDAndInventory)
+		if err != nil {
+			return err
+		}
+
+		lastEntry = entry
+	}
+	return writer.Flush()
+}
+
+// writeHledgerTransaction writes the entry, the inventories are the inventories just before it.
+func writeHledgerTransaction(writer *bufio.Writer, entry AccountingEntry, IDAndInventory AccountIDAndInventory, config PlainTextConfig) error {
+	t := time.UnixMicro(entry.TimeUnix).UTC()
+	header := t.Format(time.DateOnly)
+	if entry.Reference != "" {
+		header += " (" + entry.Reference + ")"
+	}
+	if entry.Description != "" {
+		header += " " + entry.Description
+	}
+	writer.WriteString(header + "\n")
+
+	if !t.Equal(t.Truncate(24 * time.Hour)) {
+		writer.WriteString("    ; time: " + t.Format(time.RFC3339Nano) + "\n")
+	}
+	if len(entry.Tags) != 0 {
+		writer.WriteString("    ; :" + strings.Join(entry.Tags, ":") + ":\n")
+	}
+
+	for _, single := range entry.DoubleEntry {
+		p, err := toTextPosting(single, config)
+		if err != nil {
+			return err
+		}
+
+		var lines []string
+		if p.commodity == "" {
+			lines = append(lines, formatTextNumber(p.amount)+" "+config.Currency)
+		} else {
+			layers := movedLayers(entry, single, IDAndInventory[single.AccountID])
+			isDated := layers != nil
+			if !isDated {
+				layers = Inventory{{0, single.Quantity, single.Amount}}
+			}
+			for _, layer := range layers {
+				quantity := math.Copysign(float64(layer.Quantity), float64(p.quantity))
+				lines = append(lines, formatTextNumber(quantity)+" "+p.commodity+" "+formatHledgerLot(layer, isDated, config.Currency)+
+					" @@ "+formatTextNumber(layer.Amount)+" "+config.Currency)
+			}
+		}
+		if p.costFlowType != "" {
+			lines[len(lines)-1] += "  ; cost_flow: " + p.costFlowType
+		}
+		for _, line := range lines {
+			writer.WriteString("    " + p.account + "  " + line + "\n")
+		}
+	}
+	return nil
+}
+
+// formatHledgerLot returns the lot like {10 USD} [2024-03-02] (2024-03-02T10:00:00Z) or {{100 USD}} if its price is not exact.
+func formatHledgerLot(layer InventoryRecord, isDated bool, currency string) string {
+	lot := "{{" + formatTextNumber(layer.Amount) + " " + currency + "}}"
+	if price := layer.Amount / Amount(layer.Quantity); price*Amount(layer.Quantity) == layer.Amount {
+		lot = "{" + formatTextNumber(price) + " " + currency + "}"
+	}
+
+	if isDated {
+		t := time.UnixMicro(layer.TimeUnix).UTC()
+		lot += " [" + t.Format(time.DateOnly) + "]"
+		if !t.Equal(t.Truncate(24 * time.Hour)) {
+			lot += " (" + t.Format(time.RFC3339Nano) + ")"
+		}
+	}
+	return lot
+}
+
+// hledgerPosting is a posting that ReadHledger is reading.
+type hledgerPosting struct {
+	textPosting
+	isDated bool     // true if the lot cost has the acquisition date of the lot
+	lotTime TimeUnix // the acquisition time of the lot from its date and its note
+}
+
+// hledgerTransaction is a transaction that ReadHledger is reading.
+type hledgerTransaction struct {
+	AccountingEntry
+	postings []hledgerPosting
+}
+
+// ReadHledger reads the transactions of a journal in the plain-text format of hledger and ledger-cli
+// as entries in their order in the file.
+//
+// The code of a transaction is the Reference of its entry, the description is its Description and the tags
+// like :tag: are its Tags. The entry is at the start of the day of the transaction in UTC, or at the time
+// of a time tag like "; time: 2024-03-02T10:00:00Z".
+//
+// A posting of an amount of the Currency is a line of the amount, its quantity is its amount if the Currency
+// is the commodity of its account. A posting of a quantity of another commodity should have the cost in
+// the Currency as a lot cost like {5 USD} or {{50 USD}} or as a price like @ 5 USD or @@ 50 USD.
+// The lot cost can have the acquisition date of the lot like {5 USD} [2024-03-02] and its time as the lot note like
+// (2024-03-02T10:00:00Z), the dated lots of an account in a transaction are one line with the lots as its Layers
+// if its cost flow type would not move them, and a line that takes out other lots is NONE.
+// A posting without an amount gets the amount that balances the transaction.
+// The cost flow type of a posting is the name in its cost_flow tag, or INFLOW if it increases its account,
+// NONE if it decreases its quantity or its amount only and FIFO otherwise.
+//
+// The directives like account, commodity or P and the periodic and automated transactions are skipped.
+// The inventories are followed through the entries in their order in the file to know the lots that
+// the cost flow types move, but the entries are not checked, post them with AddBatch to check them.
+//
+// Returns the entries, or an Error with the name ErrInvalidPlainText or ErrPlainTextMapping for the first invalid line.
+func ReadHledger(r io.Reader, config PlainTextConfig) ([]AccountingEntry, error) {
+	accountIDs := reverseAccounts(config)
+
+	var entries []AccountingEntry
+	var current *hledgerTransaction
+	IDAndInventory := make(AccountIDAndInventory)
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		entry, err := bookHledgerTransaction(current, IDAndInventory, config, accountIDs)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		current = nil
+
+		// an entry that can't be posted doesn't change the inventories, AddBatch reports it
+		if processed, err := CheckAndProcessDoubleEntry(0, entry, cloneInventories(IDAndInventory)); err == nil {
+			IDAndInventory = processed
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			err := finish()
+			if err != nil {
+				return nil, err
+			}
+
+		case line[0] == ' ' || line[0] == '\t':
+			if current == nil { // a line of a directive or of a skipped transaction
+				continue
+			}
+			err := readHledgerTransactionLine(lineNumber, trimmed, current, config)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			err := finish()
+			if err != nil {
+				return nil, err
+			}
+			if trimmed[0] >= '0' && trimmed[0] <= '9' {
+				current, err = readHledgerHeader(lineNumber, trimmed)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	err = finish()
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// hledgerHeader is DATE[=DATE2] [*|!] [(CODE)] [DESCRIPTION] [; COMMENT].
+var hledgerHeader = regexp.MustCompile(`^([0-9]{4}[-/.][0-9]{1,2}[-/.][0-9]{1,2})(?:=\S*)?\s*[*!]?\s*(?:\(([^)]*)\))?\s*([^;]*?)\s*(?:;(.*))?$`)
+
+func readHledgerHeader(lineNumber int, line string) (*hledgerTransaction, error) {
+	match := hledgerHeader.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fErrInvalidPlainText(lineNumber, "it is not a transaction")
+	}
+
+	date := strings.NewReplacer("/", "-", ".", "-").Replace(match[1])
+	t, err := time.Parse("2006-1-2", date)
+	if err != nil {
+		return nil, fErrInvalidPlainText(lineNumber, "its date "+match[1]+" is not a date")
+	}
+
+	current := &hledgerTransaction{}
+	current.TimeUnix = t.UnixMicro()
+	current.Reference = match[2]
+	current.Description = match[3]
+	return current, readHledgerComment(lineNumber, match[4], current, nil)
+}
+
+// readHledgerTransactionLine reads a posting or a comment of the transaction.
+func readHledgerTransactionLine(lineNumber int, line string, current *hledgerTransaction, config PlainTextConfig) error {
+	if line[0] == ';' || line[0] == '#' {
+		var p *textPosting
+		if len(current.postings) != 0 {
+			p = &current.postings[len(current.postings)-1].textPosting
+		}
+		return readHledgerComment(lineNumber, line[1:], current, p)
+	}
+
+	line, comment, _ := strings.Cut(line, ";")
+	line = strings.TrimLeft(line, "*! \t")
+
+	// the account ends with two spaces or a tab
+	end := len(line)
+	if i := strings.Index(line, "  "); i >= 0 {
+		end = i
+	}
+	if i := strings.IndexByte(line, '\t'); i >= 0 && i < end {
+		end = i
+	}
+	account, amount := strings.TrimSpace(line[:end]), line[end:]
+	if account == "" {
+		return fErrInvalidPlainText(lineNumber, "its posting has no account")
+	}
+	if account[0] == '(' || account[0] == '[' {
+		return fErrInvalidPlainText(lineNumber, "the virtual postings are not supported")
+	}
+
+	p := hledgerPosting{textPosting: textPosting{line: lineNumber, account: account}}
+	err := readHledgerAmount(lineNumber, strings.TrimSpace(amount), &p, config)
+	if err != nil {
+		return err
+	}
+	current.postings = append(current.postings, p)
+	return readHledgerComment(lineNumber, comment, current, &current.postings[len(current.postings)-1].textPosting)
+}
+
+// hledgerLotDate is the date of a lot like [2024-03-02] with its note like (2024-03-02T10:00:00Z).
+var hledgerLotDate = regexp.MustCompile(`^\[([^\]]*)\]\s*(?:\(([^)]*)\))?$`)
+
+// hledgerAmount is a number with its commodity before or after it like -5 WIDGET, $5 or USD -5.
+var hledgerAmount = regexp.MustCompile(`^(-?)\s*([^\s\d.,@{}()\[\]"-]*)\s*(-?[0-9][0-9,]*(?:\.[0-9]*)?)\s*([^\s\d.,@{}()\[\]"-]*)$`)
+
+// readHledgerAmount reads the amount of the posting with its lot cost, the date of its lot and its price,
+// an empty amount is the amount that balances the transaction.
+func readHledgerAmount(lineNumber int, expression string, p *hledgerPosting, config PlainTextConfig) error {
+	if expression == "" {
+		p.amount = Amount(math.NaN())
+		return nil
+	}
+
+	var price string
+	isTotalPrice := false
+	if before, after, ok := strings.Cut(expression, "@@"); ok {
+		expression, price, isTotalPrice = before, after, true
+	} else if before, after, ok := strings.Cut(expression, "@"); ok {
+		expression, price = before, after
+	}
+
+	var lot string
+	isTotalLot := false
+	if i := strings.Index(expression, "{"); i >= 0 {
+		lot = expression[i:]
+		expression = expression[:i]
+		end := strings.Index(lot, "}")
+		if end < 0 {
+			return fErrInvalidPlainText(lineNumber, "its lot cost has no }")
+		}
+		isTotalLot = strings.HasPrefix(lot, "{{")
+		err := readHledgerLotDate(lineNumber, strings.TrimSpace(strings.TrimLeft(lot[end:], "}")), p)
+		if err != nil {
+			return err
+		}
+		lot = strings.Trim(lot[:end], "{ ")
+	}
+
+	quantity, commodity, err := parseHledgerAmount(lineNumber, expression)
+	if err != nil {
+		return err
+	}
+	if commodity == config.Currency {
+		p.amount = Amount(quantity)
+		return nil
+	}
+	p.quantity = Quantity(quantity)
+	p.commodity = commodity
+
+	// the price is the cost if there is one because it is what balances the transaction
+	cost, isTotal := price, isTotalPrice
+	if strings.TrimSpace(price) == "" {
+		cost, isTotal = lot, isTotalLot
+	}
+	if strings.TrimSpace(cost) == "" {
+		return fErrInvalidPlainText(lineNumber, "the quantity of "+commodity+" has no cost in "+config.Currency)
+	}
+
+	costValue, costCommodity, err := parseHledgerAmount(lineNumber, strings.TrimSpace(cost))
+	if err != nil {
+		return err
+	}
+	if costCommodity != config.Currency {
+		return fErrInvalidPlainText(lineNumber, "its cost is in "+costCommodity+" instead of "+config.Currency)
+	}
+	if !isTotal {
+		costValue *= quantity
+	}
+	p.amount = Amount(math.Copysign(math.Abs(costValue), quantity))
+	return nil
+}
+
+// readHledgerLotDate reads the date of the lot after its cost and the time in its note,
+// a note that is not an RFC 3339 time is skipped.
+func readHledgerLotDate(lineNumber int, expression string, p *hledgerPosting) error {
+	if expression == "" {
+		return nil
+	}
+
+	match := hledgerLotDate.FindStringSubmatch(expression)
+	if match == nil {
+		return fErrInvalidPlainText(lineNumber, "its lot date "+expression+" is not a date like [2024-03-02]")
+	}
+	date := strings.NewReplacer("/", "-", ".", "-").Replace(match[1])
+	t, err := time.Parse("2006-1-2", date)
+	if err != nil {
+		return fErrInvalidPlainText(lineNumber, "its lot date "+match[1]+" is not a date")
+	}
+	if noteTime, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(match[2])); err == nil {
+		t = noteTime
+	}
+
+	p.isDated = true
+	p.lotTime = t.UnixMicro()
+	return nil
+}
+
+func parseHledgerAmount(lineNumber int, expression string) (float64, string, error) {
+	match := hledgerAmount.FindStringSubmatch(strings.TrimSpace(expression))
+	if match == nil || (match[2] == "") == (match[4] == "") {
+		return 0, "", fErrInvalidPlainText(lineNumber, "its amount "+strings.TrimSpace(expression)+" is not a number with a commodity")
+	}
+
+	value, err := parseTextNumber(match[3])
+	if err != nil {
+		return 0, "", fErrInvalidPlainText(lineNumber, "its amount "+strings.TrimSpace(expression)+" is not a number with a commodity")
+	}
+	if match[1] == "-" {
+		value = -value
+	}
+	return value, match[2] + match[4], nil
+}
+
+// readHledgerComment reads the tags of a comment of the transaction, or of its posting p if p is not nil.
+func readHledgerComment(lineNumber int, comment string, current *hledgerTransaction, p *textPosting) error {
+	comment = strings.TrimSpace(comment)
+	if strings.HasPrefix(comment, ":") && strings.HasSuffix(comment, ":") && len(comment) > 1 {
+		current.Tags = append(current.Tags, strings.Split(comment[1:len(comment)-1], ":")...)
+		return nil
+	}
+
+	name, value, ok := strings.Cut(comment, ":")
+	if !ok {
+		return nil
+	}
+	value = strings.TrimSpace(value)
+	switch strings.TrimSpace(name) {
+	case "time":
+		if p != nil {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fErrInvalidPlainText(lineNumber, "its time "+value+" is not RFC 3339")
+		}
+		current.TimeUnix = t.UnixMicro()
+	case "cost_flow":
+		if p != nil {
+			p.costFlowType = value
+		}
+	}
+	return nil
+}
+
+// bookHledgerTransaction returns the entry of the transaction, the postings of an account with a commodity
+// are one line, the inventories are the inventories just before the entry.
+func bookHledgerTransaction(transaction *hledgerTransaction, IDAndInventory AccountIDAndInventory, config PlainTextConfig, accountIDs map[string]AccountID) (AccountingEntry, error) {
+	var postings []textPosting
+	indexes := make(map[AccountID]int)
+	isDated := make(map[AccountID]bool)
+	lots := make(AccountIDAndInventory)
+	for _, p := range transaction.postings {
+		ID, ok := accountIDs[p.account]
+		if p.commodity == "" || !ok { // finishTextTransaction reports an account without an ID
+			postings = append(postings, p.textPosting)
+			continue
+		}
+
+		if p.isDated {
+			lots[ID] = append(lots[ID], InventoryRecord{p.lotTime, Quantity(math.Abs(float64(p.quantity))), Amount(math.Abs(float64(p.amount)))})
+		}
+
+		i, ok := indexes[ID]
+		if !ok {
+			indexes[ID] = len(postings)
+			isDated[ID] = p.isDated
+			postings = append(postings, p.textPosting)
+			continue
+		}
+
+		switch {
+		case (postings[i].quantity > 0) != (p.quantity > 0):
+			return AccountingEntry{}, fErrInvalidPlainText(p.line, "its lot is not in the direction of the lots of "+p.account+" before it")
+		case isDated[ID] != p.isDated:
+			return AccountingEntry{}, fErrInvalidPlainText(p.line, "the lots of "+p.account+" should all have a date or none of them")
+		}
+		postings[i].quantity += p.quantity
+		postings[i].amount += p.amount
+		if p.costFlowType != "" {
+			postings[i].costFlowType = p.costFlowType
+		}
+	}
+
+	entry, err := finishTextTransaction(transaction.AccountingEntry, postings, config, accountIDs)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+
+	keepLots(&entry, indexes, lots, IDAndInventory)
+	return entry, nil
+}
+
+// finishTextTransaction returns the entry of the postings, the posting with a NaN amount gets the amount that balances the others.
+func finishTextTransaction(entry AccountingEntry, postings []textPosting, config PlainTextConfig, accountIDs map[string]AccountID) (AccountingEntry, error) {
+	elided := -1
+	var sum float64
+	for i, p := range postings {
+		if math.IsNaN(float64(p.amount)) {
+			if elided >= 0 {
+				return AccountingEntry{}, fErrInvalidPlainText(p.line, "it is the second posting without an amount in its transaction")
+			}
+			elided = i
+			continue
+		}
+		sum += float64(p.amount)
+	}
+	if elided >= 0 {
+		postings[elided].amount = Amount(-sum)
+	}
+
+	for _, p := range postings {
+		single, err := fromTextPosting(p, config, accountIDs)
+		if err != nil {
+			return AccountingEntry{}, err
+		}
+		entry.DoubleEntry = append(entry.DoubleEntry, single)
+	}
+	return entry, nil
+}
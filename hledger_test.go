@@ -0,0 +1,335 @@
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+var plainTextConfig = PlainTextConfig{
+	Currency: "USD",
+	Accounts: map[AccountID]string{
+		-1001: "equity:capital",
+		-4001: "revenue:sales",
+		1001:  "assets:inventory:widgets",
+		2001:  "assets:cash",
+		3001:  "expenses:cost of goods",
+	},
+	Commodities: map[AccountID]string{1001: "WIDGET", 2001: "USD", 3001: "WIDGET"},
+}
+
+// plainTextEntries are posted to a journal to be written in the plain-text formats.
+var plainTextEntries = []AccountingEntry{
+	{TimeUnix: 1709251200000000, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}, Metadata: Metadata{Description: "capital"}},
+	{TimeUnix: 1709373600000000, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}, Metadata: Metadata{Reference: "PO-1", Description: "purchase", Tags: []string{"supplier", "q1"}}},
+	{TimeUnix: 1709460000000000, DoubleEntry: DoubleEntry{{LIFO, 1001, 4, 40}, {INFLOW, 3001, 4, 40}, {INFLOW, 2001, 60, 60}, {INFLOW, -4001, 0, 60}}, Metadata: Metadata{Reference: "INV-1", Description: "sale"}},
+}
+
+func Test_WriteHledger(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+	for _, entry := range plainTextEntries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	var b bytes.Buffer
+	fTest(WriteHledger(&b, &kk, JournalRange{}, plainTextConfig), nil)
+	fTest(b.String(), `2024-03-01 capital
+    equity:capital  -1000 USD
+    assets:cash  1000 USD
+
+2024-03-02 (PO-1) purchase
+    ; time: 2024-03-02T10:00:00Z
+    ; :supplier:q1:
+    assets:inventory:widgets  10 WIDGET {10 USD} [2024-03-02] (2024-03-02T10:00:00Z) @@ 100 USD
+    assets:cash  -100 USD  ; cost_flow: WAC
+
+2024-03-03 (INV-1) sale
+    ; time: 2024-03-03T10:00:00Z
+    assets:inventory:widgets  -4 WIDGET {10 USD} [2024-03-02] (2024-03-02T10:00:00Z) @@ 40 USD  ; cost_flow: LIFO
+    expenses:cost of goods  4 WIDGET {10 USD} [2024-03-03] (2024-03-03T10:00:00Z) @@ 40 USD
+    assets:cash  60 USD
+    revenue:sales  -60 USD
+`)
+
+	// the written journal is read as the same entries
+	entries, err := ReadHledger(&b, plainTextConfig)
+	fTest(err, nil)
+	fTest(entries, plainTextEntries)
+
+	// a line that takes out two layers is a posting for each of them, and the range writes only the sale
+	var layered myDB
+	layered.myInv = make(AccountIDAndInventory)
+	layeredEntries := []AccountingEntry{
+		{TimeUnix: 1709251200000000, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 0, 100}}},
+		{TimeUnix: 1709337600000000, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 120}, {INFLOW, -1001, 0, 120}}},
+		{TimeUnix: 1709460000000000, DoubleEntry: DoubleEntry{{FIFO, 1001, 12, 124}, {INFLOW, 3001, 12, 124}}, Metadata: Metadata{Reference: "INV-2", Description: "sale"}},
+	}
+	for _, entry := range layeredEntries {
+		_, err := AddToJournal(entry, &layered)
+		fTest(err, nil)
+	}
+
+	b.Reset()
+	fTest(WriteHledger(&b, &layered, JournalRange{From: 1709460000000000}, plainTextConfig), nil)
+	fTest(b.String(), `2024-03-03 (INV-2) sale
+    ; time: 2024-03-03T10:00:00Z
+    assets:inventory:widgets  -10 WIDGET {10 USD} [2024-03-01] @@ 100 USD
+    assets:inventory:widgets  -2 WIDGET {12 USD} [2024-03-02] @@ 24 USD
+    expenses:cost of goods  12 WIDGET {10.333333333333334 USD} [2024-03-03] (2024-03-03T10:00:00Z) @@ 124 USD
+`)
+
+	b.Reset()
+	fTest(WriteHledger(&b, &layered, JournalRange{}, plainTextConfig), nil)
+	entries, err = ReadHledger(&b, plainTextConfig)
+	fTest(err, nil)
+	fTest(entries, layeredEntries)
+
+	b.Reset()
+	config := plainTextConfig
+	config.Commodities = nil
+	err = WriteHledger(&b, &kk, JournalRange{}, config)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrPlainTextMapping : the account ID 2001 has the quantity 1000 but no commodity"))
+}
+
+func Test_ReadHledger(t *testing.T) {
+	type input struct {
+		journal string
+	}
+	type output struct {
+		AccountingEntries []AccountingEntry
+		err               error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `; migrated books
+account assets:cash
+    ; type: Asset
+commodity 1,000.00 USD
+
+P 2024/03/01 WIDGET 12 USD
+
+2024/03/01 * opening balances  ; :opening:
+    assets:cash                 1,500.50 USD
+    assets:inventory:widgets    10 WIDGET {12 USD}
+    equity:capital
+
+2024/03/05=2024/03/06 ! (S-7) sale
+    assets:inventory:widgets    -2 WIDGET @ 12 USD
+    ; cost_flow: LIFO
+	expenses:cost of goods	2 WIDGET @@ 24 USD
+    revenue:sales               -30 USD
+    assets:cash
+
+~ monthly
+    expenses:rent  100 USD
+    assets:cash
+`,
+			},
+			output: output{
+				AccountingEntries: []AccountingEntry{
+					{
+						TimeUnix:    1709251200000000,
+						DoubleEntry: DoubleEntry{{INFLOW, 2001, 1500.5, 1500.5}, {INFLOW, 1001, 10, 120}, {INFLOW, -1001, 0, 1620.5}},
+						Metadata:    Metadata{Description: "opening balances", Tags: []string{"opening"}},
+					},
+					{
+						TimeUnix:    1709596800000000,
+						DoubleEntry: DoubleEntry{{LIFO, 1001, 2, 24}, {INFLOW, 3001, 2, 24}, {INFLOW, -4001, 0, 30}, {INFLOW, 2001, 30, 30}},
+						Metadata:    Metadata{Reference: "S-7", Description: "sale"},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 purchase
+    assets:inventory:widgets  10 WIDGET {10 USD} [2024-03-01]
+    equity:capital
+
+2024-03-02 purchase
+    assets:inventory:widgets  10 WIDGET {12 USD}
+    equity:capital
+
+2024-03-03 sale
+    assets:inventory:widgets  -2 WIDGET {10 USD} [2024-03-01]
+    assets:inventory:widgets  -3 WIDGET {12 USD} [2024/03/02] (for the order S-8)
+    expenses:cost of goods  5 WIDGET @@ 56 USD
+`,
+			},
+			output: output{
+				AccountingEntries: []AccountingEntry{
+					{TimeUnix: 1709251200000000, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 0, 100}}, Metadata: Metadata{Description: "purchase"}},
+					{TimeUnix: 1709337600000000, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 120}, {INFLOW, -1001, 0, 120}}, Metadata: Metadata{Description: "purchase"}},
+					{
+						TimeUnix:    1709424000000000,
+						DoubleEntry: DoubleEntry{{NONE, 1001, 5, 56}, {INFLOW, 3001, 5, 56}},
+						Layers:      AccountIDAndInventory{1001: {{1709251200000000, 2, 20}, {1709337600000000, 3, 36}}},
+						Metadata:    Metadata{Description: "sale"},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 purchase
+    assets:inventory:widgets  10 WIDGET {10 USD} [March]
+    equity:capital
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 2 is invalid because its lot date March is not a date"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 purchase
+    assets:inventory:widgets  10 WIDGET {10 USD} [2024-03-01]
+    assets:inventory:widgets  5 WIDGET {12 USD}
+    equity:capital
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 3 is invalid because the lots of assets:inventory:widgets should all have a date or none of them"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 rent
+    expenses:rent  100 USD
+    assets:cash
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrPlainTextMapping : the account expenses:rent on line 2 has no account ID"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 purchase
+    assets:inventory:widgets  10 WIDGET
+    assets:cash
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 2 is invalid because the quantity of WIDGET has no cost in USD"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 purchase
+    assets:inventory:widgets  10 WIDGET {12 EUR}
+    assets:cash
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 2 is invalid because its cost is in EUR instead of USD"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 purchase
+    assets:cash  10 GADGET @ 1 USD
+    equity:capital
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrPlainTextMapping : the commodity GADGET on line 2 is not the commodity of the account assets:cash"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 capital
+    assets:cash
+    equity:capital
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 3 is invalid because it is the second posting without an amount in its transaction"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 capital
+    assets:cash  100 USD  ; cost_flow: FIFO
+    equity:capital
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrPlainTextMapping : the cost flow type FIFO on line 2 is not the direction of the posting"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 capital
+    (assets:cash)  100 USD
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 2 is invalid because the virtual postings are not supported"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-13-01 capital
+    assets:cash  100 USD
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 1 is invalid because its date 2024-13-01 is not a date"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				journal: `2024-03-01 capital
+    assets:cash  100 USD 5
+`,
+			},
+			output: output{
+				AccountingEntries: nil,
+				err:               fmt.Errorf("ErrInvalidPlainText : line 2 is invalid because its amount 100 USD 5 is not a number with a commodity"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.AccountingEntries, output.err = ReadHledger(strings.NewReader(tt.input.journal), plainTextConfig)
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
@@ -0,0 +1,58 @@
+package accounting
+
+import "bytes"
+
+// IdempotencyDB is a DB that finds the entries by their IdempotencyKey, an entry with a key is posted only to it.
+// GetEntryByIdempotencyKey returns the entry that was posted with the key and false if there is no such entry.
+type IdempotencyDB interface {
+	DB
+	GetEntryByIdempotencyKey(string) (AccountingEntry, bool, error)
+}
+
+// getPostedEntry returns the entry of the journal that was posted with the IdempotencyKey of the entry,
+// and false if the entry has no key or the key was not used.
+// It returns an error if the key was used for an entry with a different content,
+// the content is everything in the entry except what AddToJournal and ReverseEntry set,
+// or an Error with the name ErrUnsupportedByDB if the entry has a key and dbCommand is not an IdempotencyDB.
+func getPostedEntry(entry AccountingEntry, dbCommand DB) (AccountingEntry, bool, error) {
+	if entry.IdempotencyKey == "" {
+		return AccountingEntry{}, false, nil
+	}
+
+	idempotencyDB, ok := dbCommand.(IdempotencyDB)
+	if !ok {
+		return AccountingEntry{}, false, fErrUnsupportedByDB("find an entry by its idempotency key", "IdempotencyDB")
+	}
+
+	posted, ok, err := idempotencyDB.GetEntryByIdempotencyKey(entry.IdempotencyKey)
+	if err != nil || !ok {
+		return AccountingEntry{}, false, err
+	}
+
+	err = checkSameContent(posted, entry)
+	if err != nil {
+		return AccountingEntry{}, false, err
+	}
+	return posted, true, nil
+}
+
+// checkSameContent returns an error if the entry has a different content from the posted entry that has its IdempotencyKey.
+// The contents are compared in the canonical encoding, so a nil and an empty slice or map are the same content.
+func checkSameContent(posted AccountingEntry, entry AccountingEntry) error {
+	if !bytes.Equal(contentEncoding(posted), contentEncoding(entry)) {
+		return newError(ErrIdempotencyKeyReused, "the idempotency key %q was used for the entry with ID %v that has a different content", entry.IdempotencyKey, posted.ID)
+	}
+	return nil
+}
+
+// contentEncoding returns the canonical encoding of the entry without what AddToJournal and ReverseEntry set.
+func contentEncoding(entry AccountingEntry) []byte {
+	entry.ID = 0
+	entry.Sequence = 0
+	entry.ReversedBy = 0
+	entry.PreviousHash = Hash{}
+	entry.Hash = Hash{}
+	var e encoder
+	e.entry(entry)
+	return e.b
+}
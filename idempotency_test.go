@@ -0,0 +1,73 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+)
+
+func Test_AddToJournal_idempotencyKey(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entry := AccountingEntry{
+		TimeUnix:       1,
+		DoubleEntry:    DoubleEntry{{INFLOW, 1, 10, 100}, {INFLOW, -1, 10, 100}},
+		Metadata:       Metadata{Reference: "INV-1"},
+		IdempotencyKey: "order-1",
+	}
+
+	ID, err := AddToJournal(entry, &kk)
+	fTest(err, nil)
+	fTest(ID, 1)
+
+	// a retry returns the original entry and posts nothing
+	ID, err = AddToJournal(entry, &kk)
+	fTest(err, nil)
+	fTest(ID, 1)
+	fTest(len(kk.myEntries), 1)
+	fTest(kk.myInv[1], Inventory{{1, 10, 100}})
+
+	ID, _, err = InsertToJournal(entry, &kk)
+	fTest(err, nil)
+	fTest(ID, 1)
+	fTest(len(kk.myEntries), 1)
+
+	changed := entry
+	changed.DoubleEntry = DoubleEntry{{INFLOW, 1, 10, 200}, {INFLOW, -1, 10, 200}}
+	_, err = AddToJournal(changed, &kk)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf(`ErrIdempotencyKeyReused : the idempotency key "order-1" was used for the entry with ID 1 that has a different content`))
+
+	_, _, err = InsertToJournal(changed, &kk)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf(`ErrIdempotencyKeyReused : the idempotency key "order-1" was used for the entry with ID 1 that has a different content`))
+	fTest(len(kk.myEntries), 1)
+
+	// a retry of an entry that went through a storage is the same content even if it has empty slices instead of nil
+	stored, err := kk.myEntries[0].MarshalBinary()
+	fTest(err, nil)
+	var retried AccountingEntry
+	fTest(retried.UnmarshalBinary(stored), nil)
+	retried.Tags = []string{}
+	retried.LinesMetadata = map[AccountID]Metadata{}
+	ID, err = AddToJournal(retried, &kk)
+	fTest(err, nil)
+	fTest(ID, 1)
+
+	// the key is still usable after the entry is reversed
+	_, err = ReverseEntry(&kk, 1, 2)
+	fTest(err, nil)
+	ID, err = AddToJournal(entry, &kk)
+	fTest(err, nil)
+	fTest(ID, 1)
+
+	// entries without a key are never the same entry
+	entry.IdempotencyKey = ""
+	entry.TimeUnix = 3
+	ID, err = AddToJournal(entry, &kk)
+	fTest(err, nil)
+	fTest(ID, 3)
+	ID, err = AddToJournal(entry, &kk)
+	fTest(err, nil)
+	fTest(ID, 4)
+}
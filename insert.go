@@ -0,0 +1,188 @@
+package accounting
+
+import (
+	"maps"
+	"slices"
+)
+
+// InsertDB is a DB that can store an entry before the last entry of the journal.
+// InsertEntry stores an entry before the first journal entry with a bigger time.
+type InsertDB interface {
+	DB
+	InsertEntry(AccountingEntry) error
+}
+
+// ReplayIssue describes a later entry that becomes invalid when an entry is inserted before it.
+type ReplayIssue struct {
+	EntryID                  // the ID of the later entry
+	TimeUnix                 // the time of the later entry
+	Sequence                 // the sequence of the later entry among the entries of its time
+	Err         error        // the error that CheckAndProcessDoubleEntry gives for the later entry after the insertion
+	CostChanges []CostChange // the outflow lines of the later entry whose cost changed
+}
+
+// CostChange is an outflow line whose cost, computed by its cost flow type, is not the amount in the journal anymore.
+type CostChange struct {
+	AccountID
+	PostedAmount   Amount // the amount of the line in the journal
+	ComputedAmount Amount // the amount that the cost flow type takes out after the insertion
+}
+
+// InsertToJournal adds an entry at its real time even if later entries are already in the journal.
+//
+// Parameters:
+//   - entry: The AccountingEntry to be inserted into the journal
+//   - dbCommand: The storage of the journal and the inventories
+//
+// If an entry with the same IdempotencyKey is already posted its ID is returned like in AddToJournal.
+// If the entry is not before the last entry it is just added with AddToJournal.
+// Otherwise it goes after the entries that have the same time and gets the next Sequence.
+// Otherwise the whole journal is replayed with the entry in its chronological position
+// to recompute the inventories and the cost flows of all the entries after it.
+// Every later entry that becomes invalid, for example because there is not enough quantity
+// or because its cost of goods sold changed, is reported in a ReplayIssue. An invalid entry
+// is skipped during the replay so the issues of the entries after it are reported too.
+//
+// The journal and the inventories are changed only if there is no issue: then the entry is stored
+// with InsertEntry and the inventories of all the replayed accounts are updated.
+//
+// Returns the ID of the inserted entry, or the issues and an error if the entry itself is invalid
+// or if it makes later entries invalid, or an Error with the name ErrUnsupportedByDB if the entry is before
+// the last entry and dbCommand is not an InsertDB.
+func InsertToJournal(entry AccountingEntry, dbCommand DB) (EntryID, []ReplayIssue, error) {
+	posted, ok, err := getPostedEntry(entry, dbCommand)
+	if err != nil || ok {
+		return posted.ID, nil, err
+	}
+
+	lastEntry, err := dbCommand.GetLastEntry()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if entry.TimeUnix >= lastEntry.TimeUnix {
+		ID, err := AddToJournal(entry, dbCommand)
+		return ID, nil, err
+	}
+
+	insertDB, ok := dbCommand.(InsertDB)
+	if !ok {
+		return 0, nil, fErrUnsupportedByDB("insert an entry before the last entry", "InsertDB")
+	}
+
+	requiredDimensions, err := getRequiredDimensions(dbCommand)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = checkPostedDimensions(entry, requiredDimensions)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	journal, err := getTheJournal(dbCommand, JournalRange{})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// the entry goes after the entries that have the same time
+	i := slices.IndexFunc(journal, func(e AccountingEntry) bool {
+		return e.TimeUnix > entry.TimeUnix
+	})
+	entry.Sequence = 0
+	if i > 0 && journal[i-1].TimeUnix == entry.TimeUnix {
+		entry.Sequence = journal[i-1].Sequence + 1
+	}
+	journal = slices.Insert(journal, i, entry)
+
+	var issues []ReplayIssue
+	var lastTimeUnix TimeUnix
+	IDAndInventory := make(AccountIDAndInventory)
+	for j, e := range journal {
+		var costChanges []CostChange
+		if j > i {
+			costChanges = getCostChanges(e, IDAndInventory)
+		}
+
+		// the journal is replayed from its first entry so the inventories are not read from dbCommand
+		entryIDAndInventory, err := copyEntryInventories(e, IDAndInventory, nil)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		entryIDAndInventory, err = CheckAndProcessDoubleEntry(lastTimeUnix, e, entryIDAndInventory)
+		if err != nil {
+			if j <= i {
+				return 0, nil, err
+			}
+			issues = append(issues, ReplayIssue{e.ID, e.TimeUnix, e.Sequence, err, costChanges})
+			continue
+		}
+
+		maps.Copy(IDAndInventory, entryIDAndInventory)
+		lastTimeUnix = e.TimeUnix
+	}
+
+	if len(issues) != 0 {
+		return 0, issues, newError(ErrInsertInvalidatesLaterEntries, "inserting the entry at time %v makes %v later entries invalid", entry.TimeUnix, len(issues))
+	}
+
+	entry, err = chainEntry(entry, dbCommand)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = insertDB.InsertEntry(entry)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for ID, inv := range IDAndInventory {
+		err := dbCommand.SetInventory(ID, inv)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return entry.ID, nil, nil
+}
+
+// getTheJournal reads the entries of the journal that the range selects in order.
+func getTheJournal(dbCommand DB, journalRange JournalRange) ([]AccountingEntry, error) {
+	var journal []AccountingEntry
+	for entry, err := range dbCommand.Journal(journalRange) {
+		if err != nil {
+			return nil, err
+		}
+		journal = append(journal, entry)
+	}
+	return journal, nil
+}
+
+// getCostChanges returns the outflow lines of the entry whose cost flow type takes out
+// an amount different from the amount of the line.
+func getCostChanges(entry AccountingEntry, IDAndInventory AccountIDAndInventory) []CostChange {
+	var costChanges []CostChange
+	for _, single := range entry.DoubleEntry {
+		if entry.Layers[single.AccountID] != nil || single.Quantity <= 0 || single.Amount <= 0 {
+			continue
+		}
+
+		inventoryVariable := slices.Clone(IDAndInventory[single.AccountID])
+		switch single.CostFlowType {
+		case WAC:
+			totalQuantity, totalAmount := GetTotalInventory(inventoryVariable)
+			inventoryVariable = Inventory{{entry.TimeUnix, totalQuantity, totalAmount}}
+		case FIFO, LIFO, HIFO, LOFO:
+			sortInventoryByCostFlow(single.CostFlowType, inventoryVariable)
+		default:
+			continue
+		}
+
+		_, computedAmount := GetTotalInventory(takeLayers(single.Quantity, inventoryVariable))
+		if computedAmount != single.Amount {
+			costChanges = append(costChanges, CostChange{single.AccountID, single.Amount, computedAmount})
+		}
+	}
+	return costChanges
+}
@@ -0,0 +1,124 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_InsertToJournal(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 10, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 20, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+		{TimeUnix: 40, DoubleEntry: DoubleEntry{{FIFO, 1001, 10, 100}, {INFLOW, 3001, 10, 100}, {INFLOW, 2001, 150, 150}, {INFLOW, -4001, 10, 150}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	type input struct {
+		AccountingEntry AccountingEntry
+	}
+	type output struct {
+		EntryID      EntryID
+		ReplayIssues []ReplayIssue
+		err          error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				AccountingEntry: AccountingEntry{
+					TimeUnix:    15,
+					DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 50}, {WAC, 2001, 50, 50}},
+				},
+			},
+			output: output{
+				ReplayIssues: []ReplayIssue{
+					{3, 40, 0, fmt.Errorf("ErrAmountMismatch : amount mismatch: expected to enter amount = 50 but got = 100"), []CostChange{{1001, 100, 50}}},
+				},
+				err: fmt.Errorf("ErrInsertInvalidatesLaterEntries : inserting the entry at time 15 makes 1 later entries invalid"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				AccountingEntry: AccountingEntry{
+					TimeUnix:    25,
+					DoubleEntry: DoubleEntry{{FIFO, 1001, 10, 100}, {INFLOW, 3002, 10, 100}},
+				},
+			},
+			output: output{
+				ReplayIssues: []ReplayIssue{
+					{3, 40, 0, fmt.Errorf("ErrInventoryIsEmpty : inventory is empty"), []CostChange{{1001, 100, 0}}},
+				},
+				err: fmt.Errorf("ErrInsertInvalidatesLaterEntries : inserting the entry at time 25 makes 1 later entries invalid"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				AccountingEntry: AccountingEntry{
+					TimeUnix:    30,
+					DoubleEntry: DoubleEntry{{INFLOW, 1001, 5, 60}, {WAC, 2001, 1000, 60}},
+				},
+			},
+			output: output{
+				ReplayIssues: nil,
+				err:          fmt.Errorf("ErrInsufficientQuantityInInventory : You want to withdraw quantity = 1000 but you do not have enough quantity because your total quantity = 900"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				AccountingEntry: AccountingEntry{
+					TimeUnix:    20,
+					DoubleEntry: DoubleEntry{{INFLOW, 1001, 5, 60}, {WAC, 2001, 60, 60}},
+				},
+			},
+			output: output{
+				EntryID:      4,
+				ReplayIssues: nil,
+				err:          nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.EntryID, output.ReplayIssues, output.err = InsertToJournal(tt.input.AccountingEntry, &kk)
+		output.err = goerrors.NormalizeTheError(output.err)
+		for i := range output.ReplayIssues {
+			output.ReplayIssues[i].Err = goerrors.NormalizeTheError(output.ReplayIssues[i].Err)
+		}
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+
+	// the entry at time 20 goes after the entry that already has this time
+	type ref struct {
+		EntryID
+		TimeUnix
+		Sequence
+	}
+	var refs []ref
+	for _, entry := range kk.myEntries {
+		refs = append(refs, ref{entry.ID, entry.TimeUnix, entry.Sequence})
+	}
+	fTest(refs, []ref{{1, 10, 0}, {2, 20, 0}, {4, 20, 1}, {3, 40, 0}})
+	fTest(kk.myInv[1001], Inventory{{20, 5, 60}})
+	fTest(kk.myInv[2001], Inventory{{20, 840, 840}, {40, 150, 150}})
+
+	myInvExpected := kk.myInv
+	kk.myInv = make(AccountIDAndInventory)
+	fTest(CheckAllTheJournal(&kk), nil)
+	fTest(kk.myInv, myInvExpected)
+}
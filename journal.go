@@ -0,0 +1,25 @@
+package accounting
+
+import "slices"
+
+// JournalRange selects the entries of the journal for DB.Journal. A zero field selects every entry.
+type JournalRange struct {
+	From       TimeUnix    // the first time
+	To         TimeUnix    // the last time
+	AccountIDs []AccountID // the entries that have a line of one of these accounts
+}
+
+// Match reports whether the range selects the entry, a DB that can't select by itself can use it.
+func (r JournalRange) Match(entry AccountingEntry) bool {
+	if r.From != 0 && entry.TimeUnix < r.From || r.To != 0 && entry.TimeUnix > r.To {
+		return false
+	}
+
+	if r.AccountIDs != nil && !slices.ContainsFunc(entry.DoubleEntry, func(single SingleEntry) bool {
+		return slices.Contains(r.AccountIDs, single.AccountID)
+	}) {
+		return false
+	}
+
+	return true
+}
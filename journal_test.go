@@ -0,0 +1,64 @@
+package accounting
+
+import (
+	"testing"
+
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_JournalRange_Match(t *testing.T) {
+	entry := AccountingEntry{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1001, 1, 1}, {INFLOW, -1001, 1, 1}}}
+
+	type input struct {
+		JournalRange JournalRange
+	}
+	type output struct {
+		isMatch bool
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{testutils.GetLine(), input{JournalRange{}}, output{true}},
+		{testutils.GetLine(), input{JournalRange{From: 5, To: 5}}, output{true}},
+		{testutils.GetLine(), input{JournalRange{From: 6}}, output{false}},
+		{testutils.GetLine(), input{JournalRange{To: 4}}, output{false}},
+		{testutils.GetLine(), input{JournalRange{AccountIDs: []AccountID{2001, -1001}}}, output{true}},
+		{testutils.GetLine(), input{JournalRange{AccountIDs: []AccountID{2001}}}, output{false}},
+		{testutils.GetLine(), input{JournalRange{AccountIDs: []AccountID{}}}, output{false}},
+	}
+	for _, tt := range tests {
+		var output output
+		output.isMatch = tt.input.JournalRange.Match(entry)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_Journal(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+	for i := range 4 {
+		_, err := AddToJournal(AccountingEntry{TimeUnix: TimeUnix(i + 1), DoubleEntry: DoubleEntry{{INFLOW, AccountID(1001 + i%2), 1, 1}, {INFLOW, -1001, 1, 1}}}, &kk)
+		fTest(err, nil)
+	}
+
+	// two readers iterate at the same time and a reader can stop in the middle
+	var pairs [][2]EntryID
+	for a, err := range kk.Journal(JournalRange{AccountIDs: []AccountID{1002}}) {
+		fTest(err, nil)
+		for b, err := range kk.Journal(JournalRange{From: 2, To: 3}) {
+			fTest(err, nil)
+			pairs = append(pairs, [2]EntryID{a.ID, b.ID})
+			break
+		}
+	}
+	fTest(pairs, [][2]EntryID{{2, 2}, {4, 2}})
+
+	// the replay can run again and again
+	for range 2 {
+		kk.myInv = make(AccountIDAndInventory)
+		fTest(CheckAllTheJournal(&kk), nil)
+		fTest(kk.myInv[-1001], Inventory{{1, 1, 1}, {2, 1, 1}, {3, 1, 1}, {4, 1, 1}})
+	}
+}
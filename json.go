@@ -0,0 +1,265 @@
+package accounting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+var costFlowTypeNames = [TheNumberOfCostFlowTypes]string{
+	INFLOW: "INFLOW",
+	WAC:    "WAC",
+	FIFO:   "FIFO",
+	LIFO:   "LIFO",
+	HIFO:   "HIFO",
+	LOFO:   "LOFO",
+	NONE:   "NONE",
+}
+
+// FormatCostFlowType returns the name of the cost flow type like "FIFO".
+func FormatCostFlowType(costFlowType CostFlowType) (string, error) {
+	if costFlowType >= TheNumberOfCostFlowTypes {
+		return "", newError(ErrTheCostFlowTypeIsWrong, "the cost flow type %v has no name", costFlowType)
+	}
+	return costFlowTypeNames[costFlowType], nil
+}
+
+// ParseCostFlowType returns the cost flow type with the name like "FIFO", the case of the name matters.
+func ParseCostFlowType(name string) (CostFlowType, error) {
+	for costFlowType, typeName := range costFlowTypeNames {
+		if typeName == name {
+			return CostFlowType(costFlowType), nil
+		}
+	}
+	return 0, newError(ErrTheCostFlowTypeIsWrong, "there is no cost flow type with the name %q", name)
+}
+
+type jsonRecord struct {
+	TimeUnix TimeUnix `json:"time_unix"`
+	Quantity Quantity `json:"quantity"`
+	Amount   Amount   `json:"amount"`
+}
+
+type jsonInventory struct {
+	Version uint64       `json:"version"`
+	Records []jsonRecord `json:"records"`
+}
+
+type jsonLine struct {
+	CostFlowType string    `json:"cost_flow_type"`
+	AccountID    AccountID `json:"account_id"`
+	Quantity     Quantity  `json:"quantity"`
+	Amount       Amount    `json:"amount"`
+}
+
+type jsonMetadata struct {
+	Description  string         `json:"description,omitempty"`
+	Reference    string         `json:"reference,omitempty"`
+	Counterparty CounterpartyID `json:"counterparty,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	Dimensions   Dimensions     `json:"dimensions,omitempty"`
+}
+
+type jsonEntry struct {
+	Version        uint64                     `json:"version"`
+	ID             EntryID                    `json:"id"`
+	TimeUnix       TimeUnix                   `json:"time_unix"`
+	Sequence       Sequence                   `json:"sequence"`
+	Lines          []jsonLine                 `json:"lines"`
+	ReversalOf     EntryID                    `json:"reversal_of,omitempty"`
+	ReversedBy     EntryID                    `json:"reversed_by,omitempty"`
+	IsClosing      bool                       `json:"is_closing,omitempty"`
+	Layers         map[AccountID][]jsonRecord `json:"layers,omitempty"`
+	Metadata       *jsonMetadata              `json:"metadata,omitempty"`
+	LinesMetadata  map[AccountID]jsonMetadata `json:"lines_metadata,omitempty"`
+	IdempotencyKey string                     `json:"idempotency_key,omitempty"`
+	PreviousHash   string                     `json:"previous_hash,omitempty"` // hexadecimal, empty if it is zero
+	Hash           string                     `json:"hash,omitempty"`          // hexadecimal, empty if it is zero
+}
+
+// EncodeEntryJSON returns the JSON encoding of the entry with its EncodingVersion, the cost flow types are their names.
+// It is a function and not a json.Marshaler so AccountingEntry is still encoded by its fields where it was before.
+//
+//	{"version":2,"id":1,"time_unix":10,"sequence":0,"lines":[{"cost_flow_type":"FIFO","account_id":1001,"quantity":5,"amount":50},...],...}
+//
+// The fields that are zero are left out except the version, the ID, the time, the sequence and the lines.
+func EncodeEntryJSON(entry AccountingEntry) ([]byte, error) {
+	j := jsonEntry{
+		Version:        EncodingVersion,
+		ID:             entry.ID,
+		TimeUnix:       entry.TimeUnix,
+		Sequence:       entry.Sequence,
+		Lines:          make([]jsonLine, len(entry.DoubleEntry)),
+		ReversalOf:     entry.ReversalOf,
+		ReversedBy:     entry.ReversedBy,
+		IsClosing:      entry.IsClosing,
+		IdempotencyKey: entry.IdempotencyKey,
+		PreviousHash:   formatHash(entry.PreviousHash),
+		Hash:           formatHash(entry.Hash),
+	}
+
+	for i, single := range entry.DoubleEntry {
+		name, err := FormatCostFlowType(single.CostFlowType)
+		if err != nil {
+			return nil, err
+		}
+		j.Lines[i] = jsonLine{name, single.AccountID, single.Quantity, single.Amount}
+	}
+
+	if len(entry.Layers) != 0 {
+		j.Layers = make(map[AccountID][]jsonRecord, len(entry.Layers))
+		for ID, inv := range entry.Layers {
+			j.Layers[ID] = toJSONRecords(inv)
+		}
+	}
+
+	if !isZeroMetadata(entry.Metadata) {
+		m := jsonMetadata(entry.Metadata)
+		j.Metadata = &m
+	}
+
+	if len(entry.LinesMetadata) != 0 {
+		j.LinesMetadata = make(map[AccountID]jsonMetadata, len(entry.LinesMetadata))
+		for ID, m := range entry.LinesMetadata {
+			j.LinesMetadata[ID] = jsonMetadata(m)
+		}
+	}
+
+	return json.Marshal(j)
+}
+
+// DecodeEntryJSON decodes an entry made by EncodeEntryJSON. The fields that it does not know are ignored
+// so it reads the encodings of the versions after EncodingVersion.
+// A nil and an empty slice or map are decoded as nil.
+func DecodeEntryJSON(data []byte) (AccountingEntry, error) {
+	var j jsonEntry
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return AccountingEntry{}, fErrInvalidEncoding(err.Error())
+	}
+	if j.Version == 0 {
+		return AccountingEntry{}, fErrInvalidEncoding("the version is zero")
+	}
+
+	decoded := AccountingEntry{
+		ID:             j.ID,
+		TimeUnix:       j.TimeUnix,
+		Sequence:       j.Sequence,
+		ReversalOf:     j.ReversalOf,
+		ReversedBy:     j.ReversedBy,
+		IsClosing:      j.IsClosing,
+		IdempotencyKey: j.IdempotencyKey,
+	}
+
+	if len(j.Lines) != 0 {
+		decoded.DoubleEntry = make(DoubleEntry, len(j.Lines))
+		for i, line := range j.Lines {
+			costFlowType, err := ParseCostFlowType(line.CostFlowType)
+			if err != nil {
+				return AccountingEntry{}, err
+			}
+			decoded.DoubleEntry[i] = SingleEntry{costFlowType, line.AccountID, line.Quantity, line.Amount}
+		}
+	}
+
+	if len(j.Layers) != 0 {
+		decoded.Layers = make(AccountIDAndInventory, len(j.Layers))
+		for ID, records := range j.Layers {
+			decoded.Layers[ID] = fromJSONRecords(records)
+		}
+	}
+
+	if j.Metadata != nil {
+		decoded.Metadata = fromJSONMetadata(*j.Metadata)
+	}
+
+	if len(j.LinesMetadata) != 0 {
+		decoded.LinesMetadata = make(map[AccountID]Metadata, len(j.LinesMetadata))
+		for ID, m := range j.LinesMetadata {
+			decoded.LinesMetadata[ID] = fromJSONMetadata(m)
+		}
+	}
+
+	decoded.PreviousHash, err = parseHash(j.PreviousHash)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+	decoded.Hash, err = parseHash(j.Hash)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+	return decoded, nil
+}
+
+// EncodeInventoryJSON returns the JSON encoding of the inventory with its EncodingVersion.
+//
+//	{"version":2,"records":[{"time_unix":10,"quantity":5,"amount":50}]}
+func EncodeInventoryJSON(inv Inventory) ([]byte, error) {
+	return json.Marshal(jsonInventory{EncodingVersion, toJSONRecords(inv)})
+}
+
+// DecodeInventoryJSON decodes an inventory made by EncodeInventoryJSON, an empty inventory is decoded as nil.
+func DecodeInventoryJSON(data []byte) (Inventory, error) {
+	var j jsonInventory
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return nil, fErrInvalidEncoding(err.Error())
+	}
+	if j.Version == 0 {
+		return nil, fErrInvalidEncoding("the version is zero")
+	}
+	return fromJSONRecords(j.Records), nil
+}
+
+func toJSONRecords(inv Inventory) []jsonRecord {
+	records := make([]jsonRecord, len(inv))
+	for i, record := range inv {
+		records[i] = jsonRecord{record.TimeUnix, record.Quantity, record.Amount}
+	}
+	return records
+}
+
+func fromJSONRecords(records []jsonRecord) Inventory {
+	if len(records) == 0 {
+		return nil
+	}
+	inv := make(Inventory, len(records))
+	for i, record := range records {
+		inv[i] = InventoryRecord{record.TimeUnix, record.Quantity, record.Amount}
+	}
+	return inv
+}
+
+// fromJSONMetadata returns the metadata with nil instead of the empty tags and dimensions.
+func fromJSONMetadata(m jsonMetadata) Metadata {
+	if len(m.Tags) == 0 {
+		m.Tags = nil
+	}
+	if len(m.Dimensions) == 0 {
+		m.Dimensions = nil
+	}
+	return Metadata(m)
+}
+
+func isZeroMetadata(m Metadata) bool {
+	return m.Description == "" && m.Reference == "" && m.Counterparty == 0 && len(m.Tags) == 0 && len(m.Dimensions) == 0
+}
+
+func formatHash(h Hash) string {
+	if h == (Hash{}) {
+		return ""
+	}
+	return hex.EncodeToString(h[:])
+}
+
+func parseHash(s string) (Hash, error) {
+	var h Hash
+	if s == "" {
+		return h, nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(h) {
+		return h, newError(ErrInvalidEncoding, "the encoding is invalid because the hash %q is not %v hexadecimal digits", s, 2*len(h))
+	}
+	copy(h[:], b)
+	return h, nil
+}
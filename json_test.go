@@ -0,0 +1,141 @@
+package accounting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_ParseCostFlowType(t *testing.T) {
+	for costFlowType := range TheNumberOfCostFlowTypes {
+		name, err := FormatCostFlowType(costFlowType)
+		fTest(err, nil)
+		parsed, err := ParseCostFlowType(name)
+		fTest(err, nil)
+		fTest(parsed, costFlowType)
+	}
+
+	_, err := FormatCostFlowType(TheNumberOfCostFlowTypes)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrTheCostFlowTypeIsWrong : the cost flow type 7 has no name"))
+	_, err = ParseCostFlowType("fifo")
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrTheCostFlowTypeIsWrong : there is no cost flow type with the name \"fifo\""))
+}
+
+func Test_EncodeEntryJSON(t *testing.T) {
+	b, err := EncodeEntryJSON(fullEntry)
+	fTest(err, nil)
+	decoded, err := DecodeEntryJSON(b)
+	fTest(err, nil)
+	fTest(decoded, fullEntry)
+
+	b, err = EncodeEntryJSON(AccountingEntry{ID: 1, TimeUnix: 10, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, -4001, 5, 50}}, Metadata: Metadata{Description: "sale"}})
+	fTest(err, nil)
+	fTest(string(b), `{"version":2,"id":1,"time_unix":10,"sequence":0,"lines":[{"cost_flow_type":"FIFO","account_id":1001,"quantity":5,"amount":50},{"cost_flow_type":"INFLOW","account_id":-4001,"quantity":5,"amount":50}],"metadata":{"description":"sale"}}`)
+
+	_, err = EncodeEntryJSON(AccountingEntry{DoubleEntry: DoubleEntry{{TheNumberOfCostFlowTypes, 1, 1, 1}}})
+	fTest(errors.Is(err, ErrInvalidEntry), true)
+
+	// encoding/json still encodes the entry by its fields, so the JSON that is already stored can be read
+	b, err = json.Marshal(AccountingEntry{ID: 1, TimeUnix: 10, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}}})
+	fTest(err, nil)
+	var stored, again AccountingEntry
+	fTest(json.Unmarshal([]byte(`{"ID":1,"TimeUnix":10,"DoubleEntry":[{"CostFlowType":2,"AccountID":1001,"Quantity":5,"Amount":50}]}`), &stored), nil)
+	fTest(stored, AccountingEntry{ID: 1, TimeUnix: 10, DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}}})
+	fTest(json.Unmarshal(b, &again), nil)
+	fTest(again, stored)
+}
+
+func Test_DecodeEntryJSON(t *testing.T) {
+	type input struct {
+		data string
+	}
+	type output struct {
+		AccountingEntry AccountingEntry
+		err             error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: `{"version":3,"id":1,"time_unix":10,"lines":[{"cost_flow_type":"WAC","account_id":1001,"quantity":5,"amount":50,"unit":"kg"}],"currency":"USD"}`,
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{ID: 1, TimeUnix: 10, DoubleEntry: DoubleEntry{{WAC, 1001, 5, 50}}},
+				err:             nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: `{"version":1,"lines":[],"layers":{},"metadata":{"tags":[]},"lines_metadata":{}}`,
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: `{"id":1}`,
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because the version is zero"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: `{"version":1,"lines":[{"cost_flow_type":"AVERAGE"}]}`,
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrTheCostFlowTypeIsWrong : there is no cost flow type with the name \"AVERAGE\""),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				data: `{"version":1,"hash":"abc"}`,
+			},
+			output: output{
+				AccountingEntry: AccountingEntry{},
+				err:             fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because the hash \"abc\" is not 64 hexadecimal digits"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.AccountingEntry, output.err = DecodeEntryJSON([]byte(tt.input.data))
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_EncodeInventoryJSON(t *testing.T) {
+	for _, inv := range []Inventory{nil, {{1, 2, 20}}, {{1, 2, 20}, {-5, 0.1, 0.3}}} {
+		b, err := EncodeInventoryJSON(inv)
+		fTest(err, nil)
+		decoded, err := DecodeInventoryJSON(b)
+		fTest(err, nil)
+		fTest(decoded, inv)
+	}
+
+	b, err := EncodeInventoryJSON(Inventory{{10, 5, 50}})
+	fTest(err, nil)
+	fTest(string(b), `{"version":2,"records":[{"time_unix":10,"quantity":5,"amount":50}]}`)
+
+	_, err = DecodeInventoryJSON([]byte(`{"version":1,"records":{}}`))
+	fTest(errors.Is(err, ErrInvalidEntry), true)
+	_, err = DecodeInventoryJSON([]byte(`{"records":[]}`))
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrInvalidEncoding : the encoding is invalid because the version is zero"))
+}
@@ -0,0 +1,124 @@
+package accounting
+
+import (
+	"slices"
+	"sync"
+)
+
+// Ledger posts entries to a DB from many goroutines.
+//
+// AddToJournal of the Ledger locks only the accounts of the entry while it reads, processes and writes
+// their inventories, so entries on different accounts are posted in parallel. Checking the time,
+// giving the Sequence and the ID and writing the entry to the journal are done one entry at a time,
+// so the journal stays in time order and every entry is processed against the inventories that
+// all the entries before it in the journal left.
+//
+// The DB should be safe for concurrent use, the Ledger never calls it for the same account
+// from two goroutines at the same time.
+type Ledger struct {
+	dbCommand DB
+	exclusive sync.RWMutex // held for reading by AddToJournal and for writing by Exclusive
+	journal   sync.Mutex   // held while the entry is checked against the last entry and written to the journal
+	accounts  sync.Mutex   // guards locks
+	locks     map[AccountID]*sync.Mutex
+}
+
+// NewLedger returns a Ledger that posts to dbCommand.
+func NewLedger(dbCommand DB) *Ledger {
+	return &Ledger{dbCommand: dbCommand, locks: make(map[AccountID]*sync.Mutex)}
+}
+
+// lockAccounts locks the accounts of the entry in the order of their IDs, so two entries
+// that share accounts can't wait for each other, and returns the function that unlocks them.
+func (l *Ledger) lockAccounts(entry AccountingEntry) func() {
+	var IDs []AccountID
+	for _, single := range entry.DoubleEntry {
+		IDs = append(IDs, single.AccountID)
+	}
+	slices.Sort(IDs)
+	IDs = slices.Compact(IDs)
+
+	locks := make([]*sync.Mutex, len(IDs))
+	l.accounts.Lock()
+	for i, ID := range IDs {
+		lock, ok := l.locks[ID]
+		if !ok {
+			lock = new(sync.Mutex)
+			l.locks[ID] = lock
+		}
+		locks[i] = lock
+	}
+	l.accounts.Unlock()
+
+	for _, lock := range locks {
+		lock.Lock()
+	}
+	return func() {
+		for _, lock := range locks {
+			lock.Unlock()
+		}
+	}
+}
+
+// AddToJournal is AddToJournal that is safe to call from many goroutines.
+func (l *Ledger) AddToJournal(entry AccountingEntry) (EntryID, error) {
+	l.exclusive.RLock()
+	defer l.exclusive.RUnlock()
+
+	unlock := l.lockAccounts(entry)
+	defer unlock()
+
+	IDAndInventory := make(AccountIDAndInventory)
+	for _, single := range entry.DoubleEntry {
+		inv, err := l.dbCommand.GetInventory(single.AccountID)
+		if err != nil {
+			return 0, err
+		}
+		IDAndInventory[single.AccountID] = inv
+	}
+
+	entry, IDAndInventory, err := l.addEntryToJournal(entry, IDAndInventory)
+	if err != nil || IDAndInventory == nil {
+		return entry.ID, err
+	}
+
+	for ID, inv := range IDAndInventory {
+		err := l.dbCommand.SetInventory(ID, inv)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return entry.ID, nil
+}
+
+// addEntryToJournal processes the entry against the last entry of the journal and writes it to the journal.
+// It returns nil inventories if the entry was already posted with its IdempotencyKey.
+func (l *Ledger) addEntryToJournal(entry AccountingEntry, IDAndInventory AccountIDAndInventory) (AccountingEntry, AccountIDAndInventory, error) {
+	l.journal.Lock()
+	defer l.journal.Unlock()
+
+	entry, IDAndInventory, err := processNewEntry(entry, IDAndInventory, l.dbCommand)
+	if err != nil || IDAndInventory == nil {
+		return entry, nil, err
+	}
+
+	err = l.dbCommand.SetEntry(entry)
+	if err != nil {
+		return AccountingEntry{}, nil, err
+	}
+	return entry, IDAndInventory, nil
+}
+
+// Exclusive calls f with the DB of the Ledger while no other entry is posted,
+// for the functions that read or write many accounts like InsertToJournal, ReverseEntry,
+// AddBatch, CloseFiscalYear and CheckAllTheJournal.
+//
+//	err := ledger.Exclusive(func(db accounting.DB) error {
+//		_, err := accounting.ReverseEntry(db, ID, at)
+//		return err
+//	})
+func (l *Ledger) Exclusive(f func(DB) error) error {
+	l.exclusive.Lock()
+	defer l.exclusive.Unlock()
+	return f(l.dbCommand)
+}
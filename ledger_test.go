@@ -0,0 +1,132 @@
+package accounting
+
+import (
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// syncDB is myDB that is safe for concurrent use.
+type syncDB struct {
+	sync.Mutex
+	db myDB
+}
+
+func (s *syncDB) GetInventory(key AccountID) (Inventory, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.GetInventory(key)
+}
+func (s *syncDB) SetInventory(key AccountID, value Inventory) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.SetInventory(key, value)
+}
+func (s *syncDB) GetLastEntry() (AccountingEntry, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.GetLastEntry()
+}
+func (s *syncDB) GetLastEntryID() (EntryID, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.GetLastEntryID()
+}
+func (s *syncDB) GetEntry(ID EntryID) (AccountingEntry, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.GetEntry(ID)
+}
+func (s *syncDB) SetEntry(value AccountingEntry) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.SetEntry(value)
+}
+func (s *syncDB) InsertEntry(value AccountingEntry) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.InsertEntry(value)
+}
+func (s *syncDB) Journal(r JournalRange) iter.Seq2[AccountingEntry, error] {
+	s.Lock()
+	entries := slices.Clone(s.db.myEntries)
+	s.Unlock()
+	db := myDB{myEntries: entries}
+	return db.Journal(r)
+}
+func (s *syncDB) SetEntryReversedBy(entry EntryID, reversal EntryID) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.SetEntryReversedBy(entry, reversal)
+}
+func (s *syncDB) GetEntryByIdempotencyKey(key string) (AccountingEntry, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.db.GetEntryByIdempotencyKey(key)
+}
+
+func Test_Ledger(t *testing.T) {
+	db := &syncDB{}
+	db.db.myInv = make(AccountIDAndInventory)
+	ledger := NewLedger(db)
+
+	_, err := ledger.AddToJournal(AccountingEntry{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 100000}, {INFLOW, 2001, 100000, 100000}}})
+	fTest(err, nil)
+
+	const goroutines = 20
+	const entriesPerGoroutine = 50
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range entriesPerGoroutine {
+				// every goroutine buys into its own account with the cash that all of them share
+				_, err := ledger.AddToJournal(AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, AccountID(1000 + g), 1, 10}, {FIFO, 2001, 10, 10}}})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+			// all the goroutines post the same entry but only one is in the journal
+			_, err := ledger.AddToJournal(AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 3001, 1, 1}, {FIFO, 2001, 1, 1}}, IdempotencyKey: "once"})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	err = ledger.Exclusive(func(db DB) error {
+		entries, err := getTheJournal(db, JournalRange{})
+		fTest(err, nil)
+		fTest(len(entries), 1+goroutines*entriesPerGoroutine+1)
+
+		var count int
+		for i, entry := range entries {
+			fTest(entry.ID, EntryID(i+1))
+			if i > 0 {
+				previous := entries[i-1]
+				fTest(entry.TimeUnix > previous.TimeUnix || entry.TimeUnix == previous.TimeUnix && entry.Sequence == previous.Sequence+1, true)
+			}
+			if entry.IdempotencyKey == "once" {
+				count++
+			}
+		}
+		fTest(count, 1)
+
+		totalQuantity, totalAmount := GetTotalInventory(db.(*syncDB).db.myInv[2001])
+		fTest(totalQuantity, 100000-10*goroutines*entriesPerGoroutine-1)
+		fTest(totalAmount, 100000-10*goroutines*entriesPerGoroutine-1)
+		totalQuantity, totalAmount = GetTotalInventory(db.(*syncDB).db.myInv[1000])
+		fTest(totalQuantity, entriesPerGoroutine)
+		fTest(totalAmount, 10*entriesPerGoroutine)
+
+		myInvExpected := db.(*syncDB).db.myInv
+		db.(*syncDB).db.myInv = make(AccountIDAndInventory)
+		fTest(CheckAllTheJournal(db), nil)
+		fTest(db.(*syncDB).db.myInv, myInvExpected)
+		return nil
+	})
+	fTest(err, nil)
+}
@@ -1,10 +1,11 @@
 package accounting
 
 import (
+	"cmp"
+	"errors"
+	"iter"
 	"math"
 	"slices"
-
-	"github.com/HashemJaafar7/goerrors"
 )
 
 // errors
@@ -22,20 +23,83 @@ const (
 	ErrInsufficientAmountInInventory                             = "ErrInsufficientAmountInInventory"
 	ErrTheQuantityAndAmountShouldBeBothPositive                  = "ErrTheQuantityAndAmountShouldBeBothPositive"
 	ErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero = "ErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero"
+	ErrNoBalanceToClose                                          = "ErrNoBalanceToClose"
+	ErrEntryNotFound                                             = "ErrEntryNotFound"
+	ErrEntryIsAlreadyReversed                                    = "ErrEntryIsAlreadyReversed"
+	ErrLayersMismatch                                            = "ErrLayersMismatch"
+	ErrLayerNotFoundInInventory                                  = "ErrLayerNotFoundInInventory"
+	ErrInsertInvalidatesLaterEntries                             = "ErrInsertInvalidatesLaterEntries"
+	ErrMetadataWithoutLine                                       = "ErrMetadataWithoutLine"
+	ErrLayersWithoutLine                                         = "ErrLayersWithoutLine"
+	ErrLayerAfterEntry                                           = "ErrLayerAfterEntry"
+	ErrLayersNotByCostFlowType                                   = "ErrLayersNotByCostFlowType"
+	ErrMissingDimension                                          = "ErrMissingDimension"
+	ErrIdempotencyKeyReused                                      = "ErrIdempotencyKeyReused"
+	ErrBatchHasInvalidEntries                                    = "ErrBatchHasInvalidEntries"
+	ErrVersionConflict                                           = "ErrVersionConflict"
+	ErrCheckpointMismatch                                        = "ErrCheckpointMismatch"
+	ErrHashChainBroken                                           = "ErrHashChainBroken"
+	ErrInvalidEncoding                                           = "ErrInvalidEncoding"
+	ErrInvalidCSVField                                           = "ErrInvalidCSVField"
+	ErrCSVHasInvalidRows                                         = "ErrCSVHasInvalidRows"
+	ErrInvalidPlainText                                          = "ErrInvalidPlainText"
+	ErrPlainTextMapping                                          = "ErrPlainTextMapping"
+	ErrInvalidBankStatement                                      = "ErrInvalidBankStatement"
+	ErrUnsupportedByDB                                           = "ErrUnsupportedByDB"
 )
 
 // error functions
 
 func fErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero(ID AccountID) error {
-	return goerrors.Errorf(ErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero, "you should to use cost flow type NONE because your quantity or amount is zero for account ID %v", ID)
+	return newError(ErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero, "you should to use cost flow type NONE because your quantity or amount is zero for account ID %v", ID)
+}
+
+func fErrEntryNotFound(ID EntryID) error {
+	return newError(ErrEntryNotFound, "there is no entry with ID %v in the journal", ID)
+}
+
+func fErrHashChainBroken(ID EntryID) error {
+	return newError(ErrHashChainBroken, "the hash chain is broken at the entry with ID %v, its content, its order or the entry posted before it was changed", ID)
+}
+
+func fErrUnsupportedByDB(action string, name string) error {
+	return newError(ErrUnsupportedByDB, "the DB can't %v because it does not implement %v", action, name)
+}
+
+func fErrCheckpointMismatch(last Checkpoint) error {
+	return newError(ErrCheckpointMismatch, "the journal until the entry with ID %v is not the journal of the last checkpoint", last.EntryID)
 }
 
 func fErrInsufficientQuantityInInventory(inputQuantity, totalQuantity Quantity) error {
-	return goerrors.Errorf(ErrInsufficientQuantityInInventory, "You want to withdraw quantity = %v but you do not have enough quantity because your total quantity = %v", math.Abs(float64(inputQuantity)), totalQuantity)
+	return &InsufficientInventoryError{
+		RequestedQuantity: Quantity(math.Abs(float64(inputQuantity))),
+		AvailableQuantity: totalQuantity,
+		Err:               newError(ErrInsufficientQuantityInInventory, "You want to withdraw quantity = %v but you do not have enough quantity because your total quantity = %v", math.Abs(float64(inputQuantity)), totalQuantity),
+	}
+}
+
+func fErrInvalidEncoding(reason string) error {
+	return newError(ErrInvalidEncoding, "the encoding is invalid because %v", reason)
+}
+
+func fErrInvalidCSVField(column, value string) error {
+	return newError(ErrInvalidCSVField, "the %v %q is invalid", column, value)
+}
+
+func fErrInvalidPlainText(line int, reason string) error {
+	return newError(ErrInvalidPlainText, "line %v is invalid because %v", line, reason)
+}
+
+func fErrInvalidBankStatement(reason string) error {
+	return newError(ErrInvalidBankStatement, "the bank statement is invalid because %v", reason)
 }
 
 func fErrInsufficientAmountInInventory(inputAmount, totalAmount Amount) error {
-	return goerrors.Errorf(ErrInsufficientAmountInInventory, "You want to withdraw amount = %v but you do not have enough amount because your total amount = %v", math.Abs(float64(inputAmount)), totalAmount)
+	return &InsufficientInventoryError{
+		RequestedAmount: Amount(math.Abs(float64(inputAmount))),
+		AvailableAmount: totalAmount,
+		Err:             newError(ErrInsufficientAmountInInventory, "You want to withdraw amount = %v but you do not have enough amount because your total amount = %v", math.Abs(float64(inputAmount)), totalAmount),
+	}
 }
 
 const (
@@ -55,6 +119,8 @@ type AccountID int64
 type Quantity float64
 type Amount float64
 type TimeUnix = int64 // the time in UnixMicro()
+type Sequence uint64  // the order of an entry among the entries that have the same TimeUnix
+type EntryID uint64   // the unique ID of an entry, given by AddToJournal in increasing order
 
 type SingleEntry struct {
 	CostFlowType
@@ -66,8 +132,19 @@ type SingleEntry struct {
 type DoubleEntry []SingleEntry
 
 type AccountingEntry struct {
+	ID EntryID // set by AddToJournal
 	TimeUnix
+	Sequence // set by AddToJournal
 	DoubleEntry
+	ReversalOf EntryID               // the ID of the entry that this entry reverses, zero if it is not a reversal
+	ReversedBy EntryID               // the ID of the entry that reversed this entry, zero if it is not reversed
+	IsClosing  bool                  // the entry is a closing entry made by MakeClosingEntry
+	Layers     AccountIDAndInventory // the exact cost layers that a line adds or takes out, see CheckAndProcessDoubleEntry
+	Metadata
+	LinesMetadata  map[AccountID]Metadata // the metadata of the lines by their account ID
+	IdempotencyKey string                 // a key chosen by the client so a retried entry is posted only once, empty if there is no key
+	PreviousHash   Hash                   // the Hash of the entry posted before this entry, set by AddToJournal
+	Hash           Hash                   // the hash of this entry made by HashEntry, set by AddToJournal
 }
 
 type InventoryRecord struct {
@@ -79,12 +156,23 @@ type InventoryRecord struct {
 type Inventory []InventoryRecord
 type AccountIDAndInventory map[AccountID]Inventory
 
+// DB is the storage used by the journal functions.
+// GetLastEntry returns the last entry of the journal, or a zero AccountingEntry if the journal is empty.
+// GetLastEntryID returns the biggest ID given to an entry, or zero if the journal is empty.
+// GetEntry returns the entry with the ID and false if there is no such entry.
+// Journal returns the entries of the journal in their order that the range selects, every call returns a new iterator
+// so many readers can iterate at the same time, it yields an error and stops if the DB fails.
+//
+// The functions that need more of the storage check for it with a type assertion: InsertToJournal needs an InsertDB
+// to insert an entry before the last one, ReverseEntry needs a ReversalDB and an entry with an IdempotencyKey an IdempotencyDB.
 type DB interface {
 	GetInventory(AccountID) (Inventory, error)
 	SetInventory(AccountID, Inventory) error
-	GetLastEntryTime() (TimeUnix, error)
+	GetLastEntry() (AccountingEntry, error)
+	GetLastEntryID() (EntryID, error)
+	GetEntry(EntryID) (AccountingEntry, bool, error)
 	SetEntry(AccountingEntry) error
-	IterOnJournal() (AccountingEntry, bool, error)
+	Journal(JournalRange) iter.Seq2[AccountingEntry, error]
 }
 
 // IsNatureDebit determines if an account has a debit nature based on its ID.
@@ -123,13 +211,15 @@ func GetStatus(costFlowType CostFlowType, accountID AccountID) IsDebit {
 //
 // Returns:
 //   - AccountIDAndInventory: Updated inventory records after processing the entry
-//   - error: Error if any validation fails or processing encounters issues
+//   - error: Error if any validation fails or processing encounters issues, a LineError if it is about one line
 //
-// The function performs the following validations:
+// The function returns the first violation that ValidateEntry finds, it performs the following validations:
 //   - Ensures entry number is sequential
-//   - Verifies timestamp is after the last entry
+//   - Verifies timestamp is positive and not before the last entry, entries with the same time are ordered by their Sequence
 //   - Validates debit and credit balance
 //   - Prevents duplicate accounts in single entry
+//   - Ensures the lines metadata and the layers belong to lines of the entry
+//   - Ensures the layers that an INFLOW line adds are not after the entry
 //   - Verifies positive amounts and quantities
 //   - Ensures valid cost flow types
 //
@@ -137,49 +227,30 @@ func GetStatus(costFlowType CostFlowType, accountID AccountID) IsDebit {
 //   - Handles inflow and outflow of quantities and amounts
 //   - Manages inventory adjustments (zero quantity or amount cases)
 //   - Applies cost flow accounting methods
+//   - Adds the layers in entry.Layers for an INFLOW line and takes them out exactly for a NONE line,
+//     that is the specific identification of the layers, like when reversing an entry or carrying the layers forward.
+//     The added layers are not merged with other layers, and a layer is taken out of the records with its time and its price
+//     The layers of a line with another cost flow type should be the layers that its cost flow type takes out
 //   - Removes zero-value inventory records
 //
 // The function handles different combinations of positive, negative, and zero values
 // for both amounts and quantities, applying appropriate business rules for each case.
 
 func CheckAndProcessDoubleEntry(lastTimeUnix TimeUnix, entry AccountingEntry, accountIDAndInventoryVariable AccountIDAndInventory) (AccountIDAndInventory, error) {
-	if entry.TimeUnix <= lastTimeUnix {
-		return nil, goerrors.Errorf(ErrTimeShouldBeBigger, "time should be bigger")
-	}
-
-	totalDebit := Amount(0)
-	totalCredit := Amount(0)
-	accounts := make(map[AccountID]bool)
-	for _, single := range entry.DoubleEntry {
-		if single.CostFlowType >= TheNumberOfCostFlowTypes {
-			return nil, goerrors.Errorf(ErrTheCostFlowTypeIsWrong, "the cost flow type is wrong")
-		}
-		if single.Amount < 0 || single.Quantity < 0 {
-			return nil, goerrors.Errorf(ErrTheQuantityAndAmountShouldBeBothPositive, "the quantity and amount should be both positive for account ID %v", single.AccountID)
-		}
-		if _, exists := accounts[single.AccountID]; exists {
-			return nil, goerrors.Errorf(ErrDuplicateAccountInEntry, "duplicate account ID %v in entry", single.AccountID)
-		}
-		accounts[single.AccountID] = true
-
-		if GetStatus(single.CostFlowType, single.AccountID) {
-			totalDebit += single.Amount
-		} else {
-			totalCredit += single.Amount
+	violations := ValidateEntry(lastTimeUnix, entry)
+	if violations != nil {
+		if violations[0].LineIndex >= 0 {
+			return nil, &LineError{violations[0].LineIndex, entry.DoubleEntry[violations[0].LineIndex], violations[0].Err}
 		}
-
+		return nil, violations[0].Err
 	}
 
-	if totalDebit != totalCredit {
-		return nil, goerrors.Errorf(ErrDebitNotEqualCredit, "debit not equal credit and debit = %v , credit = %v and debit-credit = %v", totalDebit, totalCredit, totalDebit-totalCredit)
-	}
-
-	for _, single := range entry.DoubleEntry {
+	for i, single := range entry.DoubleEntry {
 		ID := single.AccountID
 
 		inventoryVariable, ok := accountIDAndInventoryVariable[ID]
 		if !ok && single.CostFlowType != INFLOW {
-			return nil, goerrors.Errorf(ErrInventoryNotFoundForAccountID, "inventory not found for account ID %v", ID)
+			return nil, &LineError{i, single, newError(ErrInventoryNotFoundForAccountID, "inventory not found for account ID %v", ID)}
 		}
 
 		qty := single.Quantity
@@ -193,34 +264,42 @@ func CheckAndProcessDoubleEntry(lastTimeUnix TimeUnix, entry AccountingEntry, ac
 		var err error
 		// i should to deal with amt == 0 and qty != 0 because i deal with amt != 0 and qty == 0 before and that will make the amount zero and quantity not zero
 		switch {
+		case entry.Layers[ID] != nil: // like reversing an entry: the line puts back or takes out exactly these layers
+			inventoryVariable, err = processLayers(single, entry.Layers[ID], inventoryVariable)
 		case amt > 0 && qty > 0:
 			inventoryVariable = append(inventoryVariable, InventoryRecord{entry.TimeUnix, qty, amt})
 		case amt > 0 && qty == 0: // not sure: but it cuse to adjust the inventory: like feeding sheep
 			inventoryVariable, err = addQuantityAndAmountOnInventory(entry.TimeUnix, qty, amt, inventoryVariable)
-		case amt > 0 && qty < 0:
-			goerrors.YouShouldNotHavePanicHere()
 		case amt == 0 && qty > 0: // not sure: like gift but i dont want this to happen because it will lead to decrease the quantity without any amount and that will make some entry verbose
 			inventoryVariable, err = addQuantityAndAmountOnInventory(entry.TimeUnix, qty, amt, inventoryVariable)
 		case amt == 0 && qty == 0:
-			return nil, goerrors.Errorf(ErrQuantityAndAmountAreZero, "you can't enter both quantity and amount as zeros for account ID %v", ID)
+			err = newError(ErrQuantityAndAmountAreZero, "you can't enter both quantity and amount as zeros for account ID %v", ID)
 		case amt == 0 && qty < 0: // not sure: it happens when the account is dont have any amount in the balance because it came from gifts or we make the amount 0
 			if single.CostFlowType != NONE {
-				return nil, fErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero(ID)
+				err = fErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero(ID)
+				break
 			}
 			inventoryVariable, err = addQuantityAndAmountOnInventory(entry.TimeUnix, qty, amt, inventoryVariable)
-		case amt < 0 && qty > 0:
-			goerrors.YouShouldNotHavePanicHere()
 		case amt < 0 && qty == 0: // not sure: but it cuse to adjust the inventory: like smashing a car or depreciation or market value
 			if single.CostFlowType != NONE {
-				return nil, fErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero(ID)
+				err = fErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero(ID)
+				break
 			}
 			inventoryVariable, err = addQuantityAndAmountOnInventory(entry.TimeUnix, qty, amt, inventoryVariable)
 		case amt < 0 && qty < 0:
 			inventoryVariable, err = checkAndProcessCostOutFlow(entry.TimeUnix, single, inventoryVariable)
+		case amt > 0 && qty < 0, amt < 0 && qty > 0:
+			err = newError(ErrQuantityAndAmountShouldBothBeDebitOrCredit, "the quantity = %v and amount = %v of account ID %v should both be debit or credit", qty, amt, ID)
+		default: // like NaN
+			err = newError(ErrTheQuantityAndAmountShouldBeBothPositive, "the quantity and amount should be both positive for account ID %v", ID)
 		}
 
 		if err != nil {
-			return nil, err
+			var insufficient *InsufficientInventoryError
+			if errors.As(err, &insufficient) {
+				insufficient.AccountID = ID
+			}
+			return nil, &LineError{i, single, err}
 		}
 
 		inventoryVariable = removeZeros(inventoryVariable)
@@ -238,6 +317,66 @@ func checkAndProcessCostOutFlow(timeVariable TimeUnix, singleEntryVariable Singl
 	case WAC:
 		totalQuantity, totalAmount := GetTotalInventory(inventoryVariable)
 		inventoryVariable = Inventory{{timeVariable, totalQuantity, totalAmount}}
+	case FIFO, LIFO, HIFO, LOFO:
+		// the layers are taken out of a sorted copy, the remaining layers keep their order in the inventory
+		// so the layers of the same time stay in the order of their Sequence
+		sorted := slices.Clone(inventoryVariable)
+		sortInventoryByCostFlow(singleEntryVariable.CostFlowType, sorted)
+		remaining, err := decreaseInventory(qty, amt, sorted)
+		if err != nil {
+			return nil, err
+		}
+		return inInventoryOrder(remaining, inventoryVariable), nil
+	case NONE:
+		return addQuantityAndAmountOnInventory(timeVariable, -qty, -amt, inventoryVariable)
+	default:
+		return nil, newError(ErrTheCostFlowTypeIsWrong, "the cost flow type %v can't take out of the inventory of account ID %v", singleEntryVariable.CostFlowType, singleEntryVariable.AccountID)
+	}
+	return decreaseInventory(qty, amt, inventoryVariable)
+}
+
+// inInventoryOrder returns the remaining layers sorted by time and, for the same time, in the order of the layers
+// of the inventory that they come from. A remaining layer is the same as its layer or a part of it with its time and its price.
+func inInventoryOrder(remaining, inventory Inventory) Inventory {
+	positions := make([]int, len(remaining))
+	isUsed := make([]bool, len(inventory))
+	find := func(isFrom func(InventoryRecord) bool) int {
+		for j, record := range inventory {
+			if !isUsed[j] && isFrom(record) {
+				isUsed[j] = true
+				return j
+			}
+		}
+		return -1
+	}
+
+	for i, record := range remaining {
+		positions[i] = find(func(layer InventoryRecord) bool { return layer == record })
+	}
+	for i, record := range remaining {
+		if positions[i] < 0 {
+			positions[i] = find(func(layer InventoryRecord) bool { return isPartOfLayer(record, layer) })
+		}
+	}
+
+	indexes := make([]int, len(remaining))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	slices.SortStableFunc(indexes, func(a, b int) int {
+		return cmp.Or(cmp.Compare(remaining[a].TimeUnix, remaining[b].TimeUnix), cmp.Compare(positions[a], positions[b]))
+	})
+
+	result := make(Inventory, len(remaining))
+	for i, index := range indexes {
+		result[i] = remaining[index]
+	}
+	return result
+}
+
+// sortInventoryByCostFlow puts the layers in the order that the cost flow type takes them out.
+func sortInventoryByCostFlow(costFlowType CostFlowType, inventoryVariable Inventory) {
+	switch costFlowType {
 	case FIFO:
 		SortInventoryByTime(inventoryVariable)
 	case LIFO:
@@ -248,16 +387,98 @@ func checkAndProcessCostOutFlow(timeVariable TimeUnix, singleEntryVariable Singl
 		slices.Reverse(inventoryVariable)
 	case LOFO:
 		SortInventoryByPrice(inventoryVariable)
-	case NONE:
-		return addQuantityAndAmountOnInventory(timeVariable, -qty, -amt, inventoryVariable)
-	default:
-		goerrors.YouShouldNotHavePanicHere()
 	}
-	return decreaseInventory(qty, amt, inventoryVariable)
 }
 
+func processLayers(singleEntryVariable SingleEntry, layers Inventory, inventoryVariable Inventory) (Inventory, error) {
+	ID := singleEntryVariable.AccountID
+	if singleEntryVariable.Quantity == 0 && singleEntryVariable.Amount == 0 {
+		return nil, newError(ErrQuantityAndAmountAreZero, "you can't enter both quantity and amount as zeros for account ID %v", ID)
+	}
+
+	isInflow := singleEntryVariable.CostFlowType == INFLOW
+	if !isInflow && singleEntryVariable.CostFlowType != NONE && !slices.Equal(movedLayers(AccountingEntry{}, singleEntryVariable, inventoryVariable), layers) {
+		return nil, newError(ErrLayersNotByCostFlowType, "the layers of account ID %v are not the layers that its cost flow type %v takes out, use the cost flow type NONE to take out other layers", ID, singleEntryVariable.CostFlowType)
+	}
+
+	for _, layer := range layers {
+		if layer.Quantity < 0 || layer.Amount < 0 {
+			return nil, newError(ErrTheQuantityAndAmountShouldBeBothPositive, "the quantity and amount should be both positive for account ID %v", ID)
+		}
+	}
+
+	totalQuantity, totalAmount := GetTotalInventory(layers)
+	if totalQuantity != singleEntryVariable.Quantity || totalAmount != singleEntryVariable.Amount {
+		return nil, newError(ErrLayersMismatch, "the layers of account ID %v add up to quantity = %v and amount = %v but the line has quantity = %v and amount = %v", ID, totalQuantity, totalAmount, singleEntryVariable.Quantity, singleEntryVariable.Amount)
+	}
+
+	inventoryVariable = slices.Clone(inventoryVariable)
+	if isInflow {
+		// the layers are not merged with the layers of the same time, so they can be taken out exactly again
+		inventoryVariable = append(inventoryVariable, layers...)
+		SortInventoryByTime(inventoryVariable)
+		return inventoryVariable, nil
+	}
+
+	for _, layer := range layers {
+		if !takeLayer(layer, inventoryVariable) {
+			return nil, newError(ErrLayerNotFoundInInventory, "the layer %v of account ID %v is not found in the inventory", layer, ID)
+		}
+	}
+
+	SortInventoryByTime(inventoryVariable)
+	return inventoryVariable, nil
+}
+
+// takeLayer takes the layer out of the records with its time and its price in their order,
+// it returns false if they don't have enough of it.
+func takeLayer(layer InventoryRecord, inventoryVariable Inventory) bool {
+	remaining := layer
+	for i := range inventoryVariable {
+		record := &inventoryVariable[i]
+		if !isPartOfLayer(*record, layer) {
+			continue
+		}
+
+		// a layer without quantity is measured by its amount
+		recordSize, remainingSize := float64(record.Quantity), float64(remaining.Quantity)
+		if layer.Quantity == 0 {
+			recordSize, remainingSize = float64(record.Amount), float64(remaining.Amount)
+		}
+
+		switch {
+		case recordSize < remainingSize:
+			remaining.Quantity -= record.Quantity
+			remaining.Amount -= record.Amount
+			*record = InventoryRecord{record.TimeUnix, 0, 0}
+		case recordSize == remainingSize:
+			*record = InventoryRecord{record.TimeUnix, 0, 0}
+			return true
+		default:
+			record.Quantity -= remaining.Quantity
+			record.Amount -= remaining.Amount
+			return true
+		}
+	}
+	return false
+}
+
+// isPartOfLayer reports if the record has the time and the price of the layer.
+func isPartOfLayer(record, layer InventoryRecord) bool {
+	if record.TimeUnix != layer.TimeUnix || (record.Quantity == 0) != (layer.Quantity == 0) || record.Amount == 0 && record.Quantity == 0 {
+		return false
+	}
+	if layer.Quantity == 0 {
+		return true
+	}
+	price1 := float64(record.Amount) / float64(record.Quantity)
+	price2 := float64(layer.Amount) / float64(layer.Quantity)
+	return math.Abs(price1-price2) <= 1e-9*math.Max(math.Abs(price1), math.Abs(price2))
+}
+
+// SortInventoryByPrice sorts the layers from the lowest to the highest price, layers with the same price keep their order.
 func SortInventoryByPrice(inventory Inventory) {
-	slices.SortFunc(inventory, func(a, b InventoryRecord) int {
+	slices.SortStableFunc(inventory, func(a, b InventoryRecord) int {
 		price1 := a.Amount / Amount(a.Quantity)
 		price2 := b.Amount / Amount(b.Quantity)
 		switch {
@@ -271,8 +492,10 @@ func SortInventoryByPrice(inventory Inventory) {
 	})
 }
 
+// SortInventoryByTime sorts the layers from the oldest to the newest, layers with the same time keep their order
+// so the layers of entries with the same time are taken in the order of their Sequence.
 func SortInventoryByTime(inventory Inventory) {
-	slices.SortFunc(inventory, func(a, b InventoryRecord) int {
+	slices.SortStableFunc(inventory, func(a, b InventoryRecord) int {
 		switch {
 		case a.TimeUnix > b.TimeUnix:
 			return 1
@@ -296,7 +519,7 @@ func GetTotalInventory(inventory Inventory) (Quantity, Amount) {
 
 func decreaseInventory(qty Quantity, amt Amount, inventoryVariable Inventory) (Inventory, error) {
 	if len(inventoryVariable) == 0 {
-		return nil, goerrors.Errorf(ErrInventoryIsEmpty, "inventory is empty")
+		return nil, newError(ErrInventoryIsEmpty, "inventory is empty")
 	}
 
 	totalQty, totalAmt := GetTotalInventory(inventoryVariable)
@@ -345,7 +568,7 @@ func decreaseInventory(qty Quantity, amt Amount, inventoryVariable Inventory) (I
 	}
 
 	if amtAccumulator != amt {
-		return nil, goerrors.Errorf(ErrAmountMismatch, "amount mismatch: expected to enter amount = %v but got = %v", amtAccumulator, amt)
+		return nil, newError(ErrAmountMismatch, "amount mismatch: expected to enter amount = %v but got = %v", amtAccumulator, amt)
 	}
 
 	return resultInventory, nil
@@ -388,82 +611,156 @@ func removeZeros(inventoryVariable Inventory) Inventory {
 //   - setEntryFunction: A function to save a new entry to the journal
 //
 // The function performs the following steps:
-// 1. Retrieves current inventory for all accounts involved in the entry
-// 2. Gets the last journal entry for reference
-// 3. Validates and processes the double-entry accounting rules
-// 4. Saves the new entry to the journal
-// 5. Updates the inventory for all affected accounts
+// 1. Returns the ID of the posted entry if an entry with the same IdempotencyKey and the same content is already in the journal
+// 2. Retrieves current inventory for all accounts involved in the entry
+// 3. Gets the last journal entry for reference
+// 4. Validates and processes the double-entry accounting rules
+// 5. Saves the new entry to the journal with a new ID, its hash chained to the entry posted before it, and with the next Sequence if it has the same time as the last entry
+// 6. Updates the inventory for all affected accounts
 //
-// Returns an error if any operation fails during the process.
-func AddToJournal(entry AccountingEntry, dbCommand DB) error {
+// Returns the ID of the new entry that can be used with GetEntry,
+// or an error if any operation fails during the process or if the IdempotencyKey was used for an entry with a different content.
+func AddToJournal(entry AccountingEntry, dbCommand DB) (EntryID, error) {
+	entry, err := addToJournal(entry, dbCommand)
+	if err != nil {
+		return 0, err
+	}
+	return entry.ID, nil
+}
 
+// addToJournal is AddToJournal that returns the entry as it is stored in the journal.
+func addToJournal(entry AccountingEntry, dbCommand DB) (AccountingEntry, error) {
 	IDAndInventory := make(AccountIDAndInventory)
 	for _, singleEntryVariable := range entry.DoubleEntry {
 		inv, err := dbCommand.GetInventory(singleEntryVariable.AccountID)
 		if err != nil {
-			return err
+			return AccountingEntry{}, err
 		}
 		IDAndInventory[singleEntryVariable.AccountID] = inv
 	}
 
-	lastEntryTime, err := dbCommand.GetLastEntryTime()
-	if err != nil {
-		return err
-	}
-
-	IDAndInventory, err = CheckAndProcessDoubleEntry(lastEntryTime, entry, IDAndInventory)
-	if err != nil {
-		return err
+	entry, IDAndInventory, err := processNewEntry(entry, IDAndInventory, dbCommand)
+	if err != nil || IDAndInventory == nil {
+		return entry, err
 	}
 
 	err = dbCommand.SetEntry(entry)
 	if err != nil {
-		return err
+		return AccountingEntry{}, err
 	}
 
 	for ID, inv := range IDAndInventory {
 		err := dbCommand.SetInventory(ID, inv)
 		if err != nil {
-			return err
+			return AccountingEntry{}, err
 		}
 	}
 
-	return nil
+	return entry, nil
 }
 
-// CheckAllTheJournal iterates through journal entries and processes double-entry accounting
-// by updating account inventories. It takes two function parameters:
-//
-// setInventoryFunction: A function that updates the inventory for a given ID
-// iterOnJournalFunction: A function that iterates through journal entries
-//
-// The function processes entries sequentially, checking and validating double-entry accounting rules.
-// For each processed entry, it updates an in-memory map of ID inventories.
-// Finally, it persists all updated inventories using the setInventoryFunction.
-//
-// Returns an error if any operation fails during journal processing or inventory updates.
-func CheckAllTheJournal(dbCommand DB) error {
+// processNewEntry is the posting of an entry without its writes, every function that posts one entry uses it.
+// It returns the posted entry and nil inventories if the IdempotencyKey of the entry is already posted,
+// otherwise it processes the entry against the inventories of its accounts and the last entry of the journal
+// like processEntryAfter.
+func processNewEntry(entry AccountingEntry, IDAndInventory AccountIDAndInventory, dbCommand DB) (AccountingEntry, AccountIDAndInventory, error) {
+	posted, ok, err := getPostedEntry(entry, dbCommand)
+	if err != nil || ok {
+		return posted, nil, err
+	}
+
+	lastEntry, err := dbCommand.GetLastEntry()
+	if err != nil {
+		return AccountingEntry{}, nil, err
+	}
 
+	lastID, lastHash, err := getLastPosted(dbCommand)
+	if err != nil {
+		return AccountingEntry{}, nil, err
+	}
+
+	requiredDimensions, err := getRequiredDimensions(dbCommand)
+	if err != nil {
+		return AccountingEntry{}, nil, err
+	}
+
+	return processEntryAfter(entry, IDAndInventory, lastEntry, lastID, lastHash, requiredDimensions)
+}
+
+// processEntryAfter checks and processes the entry after the last entry in time and returns it with the next Sequence
+// if it has the same time, with the ID after lastID and chained to lastHash, and with the new inventories.
+// The entry should have the required dimensions unless it is a closing entry.
+func processEntryAfter(entry AccountingEntry, IDAndInventory AccountIDAndInventory, lastEntry AccountingEntry, lastID EntryID, lastHash Hash, requiredDimensions RequiredDimensions) (AccountingEntry, AccountIDAndInventory, error) {
+	IDAndInventory, err := CheckAndProcessDoubleEntry(lastEntry.TimeUnix, entry, IDAndInventory)
+	if err != nil {
+		return AccountingEntry{}, nil, err
+	}
+
+	err = checkPostedDimensions(entry, requiredDimensions)
+	if err != nil {
+		return AccountingEntry{}, nil, err
+	}
+
+	entry.Sequence = 0
+	if entry.TimeUnix == lastEntry.TimeUnix {
+		entry.Sequence = lastEntry.Sequence + 1
+	}
+
+	return chainAfter(entry, lastID, lastHash), IDAndInventory, nil
+}
+
+// replayTheJournal processes all the journal and checks its hash chain and the order of its entries by their time
+// and their Sequence, and returns the inventories it gives.
+func replayTheJournal(dbCommand DB) (AccountIDAndInventory, error) {
 	var lastEntry AccountingEntry
 	IDAndInventory := make(AccountIDAndInventory)
-	for {
-		entry, isContinue, err := dbCommand.IterOnJournal()
+	chain := make(hashChain)
+	for entry, err := range dbCommand.Journal(JournalRange{}) {
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		if !isContinue {
-			break
+		chain.add(entry)
+		err = checkJournalOrder(lastEntry.ID == 0, lastEntry.TimeUnix, lastEntry.Sequence, entry)
+		if err != nil {
+			return nil, err
 		}
 
 		IDAndInventory, err = CheckAndProcessDoubleEntry(lastEntry.TimeUnix, entry, IDAndInventory)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		lastEntry = entry
 	}
 
+	err := chain.check()
+	if err != nil {
+		return nil, err
+	}
+	return IDAndInventory, nil
+}
+
+// CheckAllTheJournal iterates through journal entries and processes double-entry accounting
+// by updating account inventories. It takes two function parameters:
+//
+// setInventoryFunction: A function that updates the inventory for a given ID
+// Journal: A function that returns an iterator on the journal entries
+//
+// The function processes entries sequentially, checking and validating double-entry accounting rules.
+// For each processed entry, it updates an in-memory map of ID inventories.
+// Finally, it persists all updated inventories using the setInventoryFunction.
+//
+// Returns an error if any operation fails during journal processing or inventory updates,
+// or an Error with the name ErrHashChainBroken for a changed entry or for an entry that is not in the order of its
+// time and its Sequence.
+//
+// Use FindInventoryDrift to compare the inventories with the journal without writing them.
+func CheckAllTheJournal(dbCommand DB) error {
+	IDAndInventory, err := replayTheJournal(dbCommand)
+	if err != nil {
+		return err
+	}
+
 	for ID, inv := range IDAndInventory {
 		err := dbCommand.SetInventory(ID, inv)
 		if err != nil {
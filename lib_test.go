@@ -1,7 +1,11 @@
 package accounting
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"iter"
+	"slices"
 	"testing"
 
 	"github.com/HashemJaafar7/goerrors"
@@ -926,10 +930,10 @@ func Test_removeZeros(t *testing.T) {
 }
 
 type myDB struct {
-	myInv         AccountIDAndInventory
-	myEntries     []AccountingEntry
-	lastEntryTime TimeUnix
-	i             int
+	myInv       AccountIDAndInventory
+	myEntries   []AccountingEntry
+	lastEntry   AccountingEntry
+	lastEntryID EntryID
 }
 
 func (s *myDB) GetInventory(key AccountID) (Inventory, error) {
@@ -943,22 +947,90 @@ func (s *myDB) SetInventory(key AccountID, value Inventory) error {
 	s.myInv[key] = value
 	return nil
 }
-func (s *myDB) GetLastEntryTime() (TimeUnix, error) {
-	return s.lastEntryTime, nil
+func (s *myDB) GetLastEntry() (AccountingEntry, error) {
+	return s.lastEntry, nil
+}
+func (s *myDB) GetLastEntryID() (EntryID, error) {
+	return s.lastEntryID, nil
+}
+func (s *myDB) GetEntry(ID EntryID) (AccountingEntry, bool, error) {
+	for _, e := range s.myEntries {
+		if e.ID == ID {
+			return e, true, nil
+		}
+	}
+	return AccountingEntry{}, false, nil
 }
 func (s *myDB) SetEntry(value AccountingEntry) error {
-	s.lastEntryTime = value.TimeUnix
+	s.lastEntry = value
+	s.lastEntryID = value.ID
 	s.myEntries = append(s.myEntries, value)
 	return nil
 }
-func (s *myDB) IterOnJournal() (AccountingEntry, bool, error) {
-	if len(s.myEntries) == s.i {
-		return AccountingEntry{}, false, nil
+func (s *myDB) InsertEntry(value AccountingEntry) error {
+	i := slices.IndexFunc(s.myEntries, func(e AccountingEntry) bool {
+		return e.TimeUnix > value.TimeUnix
+	})
+	if i == -1 {
+		return s.SetEntry(value)
+	}
+	s.myEntries = slices.Insert(s.myEntries, i, value)
+	s.lastEntryID = value.ID
+	return nil
+}
+func (s *myDB) Journal(r JournalRange) iter.Seq2[AccountingEntry, error] {
+	return func(yield func(AccountingEntry, error) bool) {
+		for _, e := range s.myEntries {
+			if r.Match(e) && !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+func (s *myDB) SetEntryReversedBy(entry EntryID, reversal EntryID) error {
+	for i := range s.myEntries {
+		if s.myEntries[i].ID == entry {
+			s.myEntries[i].ReversedBy = reversal
+		}
+	}
+	return nil
+}
+func (s *myDB) GetEntryByIdempotencyKey(key string) (AccountingEntry, bool, error) {
+	for _, e := range s.myEntries {
+		if e.IdempotencyKey == key {
+			return e, true, nil
+		}
 	}
+	return AccountingEntry{}, false, nil
+}
+
+// coreDB is myDB with only the methods of DB.
+type coreDB struct {
+	DB
+}
+
+func Test_DB_optionalInterfaces(t *testing.T) {
+	kk := &myDB{myInv: make(AccountIDAndInventory)}
+	db := coreDB{kk}
 
-	a := s.myEntries[s.i]
-	s.i++
-	return a, true, nil
+	ID, err := AddToJournal(AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}}, db)
+	fTest(err, nil)
+
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 5}, {INFLOW, 2001, 5, 5}}, IdempotencyKey: "key"}, db)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrUnsupportedByDB : the DB can't find an entry by its idempotency key because it does not implement IdempotencyDB"))
+	_, err = AddToJournalContext(context.Background(), AccountingEntry{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 5}, {INFLOW, 2001, 5, 5}}, IdempotencyKey: "key"}, NewContextDB(db))
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrUnsupportedByDB : the DB can't find an entry by its idempotency key because it does not implement IdempotencyDB"))
+
+	_, _, err = InsertToJournal(AccountingEntry{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 5}, {INFLOW, 2001, 5, 5}}}, db)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrUnsupportedByDB : the DB can't insert an entry before the last entry because it does not implement InsertDB"))
+
+	_, err = ReverseEntry(db, ID, 3)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrUnsupportedByDB : the DB can't mark an entry as reversed because it does not implement ReversalDB"))
+	fTest(len(kk.myEntries), 1)
+
+	// the DB that has the optional methods
+	_, err = AddToJournalContext(context.Background(), AccountingEntry{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 5}, {INFLOW, 2001, 5, 5}}, IdempotencyKey: "key"}, NewContextDB(kk))
+	fTest(err, nil)
 }
 
 func Test_AddToJournal(t *testing.T) {
@@ -1143,7 +1215,7 @@ func Test_AddToJournal(t *testing.T) {
 	}
 	for _, tt := range tests {
 		var output output
-		output.err = AddToJournal(tt.input.AccountingEntry, &kk)
+		_, output.err = AddToJournal(tt.input.AccountingEntry, &kk)
 		output.err = goerrors.NormalizeTheError(output.err)
 		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
 	}
@@ -1659,3 +1731,90 @@ func Test_CheckAndProcessDoubleEntry(t *testing.T) {
 		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
 	}
 }
+
+func Test_AddToJournal_sameTime(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1, 10, 100}, {INFLOW, -1, 10, 100}}},
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1, 10, 200}, {INFLOW, -1, 10, 200}}},
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1, 10, 300}, {INFLOW, -1, 10, 300}}},
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{FIFO, 1, 10, 100}, {INFLOW, 2, 10, 100}}},
+		{TimeUnix: 6, DoubleEntry: DoubleEntry{{LIFO, 1, 10, 300}, {INFLOW, 2, 10, 300}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	var sequences []Sequence
+	var IDs []EntryID
+	for _, entry := range kk.myEntries {
+		sequences = append(sequences, entry.Sequence)
+		IDs = append(IDs, entry.ID)
+	}
+	fTest(sequences, []Sequence{0, 1, 2, 3, 0})
+	fTest(IDs, []EntryID{1, 2, 3, 4, 5})
+	fTest(kk.myInv[1], Inventory{{5, 10, 200}})
+
+	entry, isFound, err := kk.GetEntry(4)
+	fTest(isFound, true)
+	fTest(err, nil)
+	fTest(entry, AccountingEntry{ID: 4, TimeUnix: 5, Sequence: 3, DoubleEntry: DoubleEntry{{FIFO, 1, 10, 100}, {INFLOW, 2, 10, 100}},
+		PreviousHash: kk.myEntries[2].Hash, Hash: entry.Hash})
+
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1, 10, 100}, {INFLOW, -1, 10, 100}}}, &kk)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrTimeShouldBeBigger : time should be bigger"))
+
+	kk = myDB{myInv: make(AccountIDAndInventory)}
+
+	// the LIFO sale keeps the layers of the same time in the order of their Sequence, so FIFO takes the layer of cost 100 first
+	entries = []AccountingEntry{
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1, 10, 100}, {INFLOW, -1, 10, 100}}},
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1, 10, 200}, {INFLOW, -1, 10, 200}}},
+		{TimeUnix: 6, DoubleEntry: DoubleEntry{{INFLOW, 1, 10, 300}, {INFLOW, -1, 10, 300}}},
+		{TimeUnix: 7, DoubleEntry: DoubleEntry{{LIFO, 1, 5, 150}, {INFLOW, 2, 5, 150}}},
+		{TimeUnix: 8, DoubleEntry: DoubleEntry{{FIFO, 1, 10, 100}, {INFLOW, 2, 10, 100}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+	fTest(kk.myInv[1], Inventory{{5, 10, 200}, {6, 5, 150}})
+}
+
+func Fuzz_CheckAndProcessDoubleEntry(f *testing.F) {
+	f.Add(int64(0), int64(1), uint8(INFLOW), int8(1), 10.0, 100.0, uint8(INFLOW), int8(-1), 10.0, 100.0, uint8(NONE), int8(2), 0.0, 0.0, 5.0, 50.0, false)
+	f.Add(int64(1), int64(2), uint8(FIFO), int8(1), 5.0, 50.0, uint8(INFLOW), int8(2), 5.0, 50.0, uint8(NONE), int8(3), 0.0, 0.0, 10.0, 100.0, true)
+	f.Add(int64(1), int64(2), uint8(NONE), int8(1), 5.0, 0.0, uint8(NONE), int8(-1), 0.0, 50.0, uint8(WAC), int8(2), 1.0, 50.0, 10.0, 100.0, false)
+
+	f.Fuzz(func(t *testing.T, lastTimeUnix, timeUnix int64,
+		c1 uint8, a1 int8, q1, m1 float64,
+		c2 uint8, a2 int8, q2, m2 float64,
+		c3 uint8, a3 int8, q3, m3 float64,
+		invQuantity, invAmount float64, withLayers bool) {
+		entry := AccountingEntry{
+			TimeUnix: timeUnix,
+			DoubleEntry: DoubleEntry{
+				{CostFlowType(c1), AccountID(a1), Quantity(q1), Amount(m1)},
+				{CostFlowType(c2), AccountID(a2), Quantity(q2), Amount(m2)},
+				{CostFlowType(c3), AccountID(a3), Quantity(q3), Amount(m3)},
+			},
+		}
+		if withLayers {
+			entry.Layers = AccountIDAndInventory{AccountID(a1): Inventory{{lastTimeUnix, Quantity(q1), Amount(m1)}}}
+		}
+
+		inventories := AccountIDAndInventory{
+			AccountID(a1): Inventory{{lastTimeUnix, Quantity(invQuantity), Amount(invAmount)}},
+			AccountID(a2): Inventory{{lastTimeUnix, Quantity(invAmount), Amount(invQuantity)}, {lastTimeUnix + 1, 1, 1}},
+		}
+
+		_, err := CheckAndProcessDoubleEntry(lastTimeUnix, entry, inventories)
+		var lineError *LineError
+		if errors.As(err, &lineError) && !errors.Is(err, ErrInvalidLine) {
+			t.Fatalf("the LineError %v is not ErrInvalidLine", err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package accounting
+
+import (
+	"slices"
+	"strings"
+)
+
+type CounterpartyID int64
+
+// Metadata is optional information about an entry or about a line of an entry.
+type Metadata struct {
+	Description  string
+	Reference    string         // an external reference like an invoice or a document number
+	Counterparty CounterpartyID // zero if there is no counterparty
+	Tags         []string
+	Dimensions   Dimensions // the analytic dimensions, the dimensions of an entry are for all its lines
+}
+
+// MetadataFilter selects entries by their metadata and the metadata of their lines.
+// A zero field matches every entry.
+type MetadataFilter struct {
+	Text         string         // a part of a description or of a reference, the case is ignored
+	Reference    string         // a whole reference
+	Counterparty CounterpartyID // a counterparty
+	Tags         []string       // every one of these tags
+}
+
+// MatchMetadata reports whether the entry matches the filter.
+// The metadata of the entry and of all its lines are searched together,
+// so a filter can match the description of the entry and a tag of one of its lines.
+func MatchMetadata(filter MetadataFilter, entry AccountingEntry) bool {
+	all := []Metadata{entry.Metadata}
+	for _, single := range entry.DoubleEntry {
+		if metadata, ok := entry.LinesMetadata[single.AccountID]; ok {
+			all = append(all, metadata)
+		}
+	}
+
+	matchAny := func(isMatch func(Metadata) bool) bool {
+		return slices.ContainsFunc(all, isMatch)
+	}
+
+	text := strings.ToLower(filter.Text)
+	if text != "" && !matchAny(func(m Metadata) bool {
+		return strings.Contains(strings.ToLower(m.Description), text) || strings.Contains(strings.ToLower(m.Reference), text)
+	}) {
+		return false
+	}
+
+	if filter.Reference != "" && !matchAny(func(m Metadata) bool { return m.Reference == filter.Reference }) {
+		return false
+	}
+
+	if filter.Counterparty != 0 && !matchAny(func(m Metadata) bool { return m.Counterparty == filter.Counterparty }) {
+		return false
+	}
+
+	for _, tag := range filter.Tags {
+		if !matchAny(func(m Metadata) bool { return slices.Contains(m.Tags, tag) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SearchJournal returns the entries of the journal that match the filter, in the order of the journal.
+func SearchJournal(filter MetadataFilter, dbCommand DB) ([]AccountingEntry, error) {
+	journal, err := getTheJournal(dbCommand, JournalRange{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []AccountingEntry
+	for _, entry := range journal {
+		if MatchMetadata(filter, entry) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_MatchMetadata(t *testing.T) {
+	entry := AccountingEntry{
+		TimeUnix: 1,
+		DoubleEntry: DoubleEntry{
+			{INFLOW, 1001, 10, 100},
+			{WAC, 2001, 100, 100},
+		},
+		Metadata: Metadata{Description: "Purchase of goods", Reference: "INV-42", Counterparty: 7},
+		LinesMetadata: map[AccountID]Metadata{
+			1001: {Description: "ten boxes", Tags: []string{"warehouse", "boxes"}},
+			2001: {Reference: "CHQ-9"},
+		},
+	}
+
+	type input struct {
+		MetadataFilter MetadataFilter
+	}
+	type output struct {
+		isMatch bool
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{}},
+			output: output{isMatch: true},
+		},
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{Text: "purchase"}},
+			output: output{isMatch: true},
+		},
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{Text: "BOXES"}},
+			output: output{isMatch: true},
+		},
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{Text: "sale"}},
+			output: output{isMatch: false},
+		},
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{Reference: "CHQ-9", Counterparty: 7}},
+			output: output{isMatch: true},
+		},
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{Reference: "CHQ"}},
+			output: output{isMatch: false},
+		},
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{Counterparty: 8}},
+			output: output{isMatch: false},
+		},
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{Text: "inv-", Tags: []string{"boxes", "warehouse"}}},
+			output: output{isMatch: true},
+		},
+		{
+			line:   testutils.GetLine(),
+			input:  input{MetadataFilter: MetadataFilter{Tags: []string{"boxes", "office"}}},
+			output: output{isMatch: false},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.isMatch = MatchMetadata(tt.input.MetadataFilter, entry)
+
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_SearchJournal(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}, Metadata: Metadata{Description: "capital"}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}, Metadata: Metadata{Reference: "INV-1", Counterparty: 3}},
+		{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}, LinesMetadata: map[AccountID]Metadata{1001: {Reference: "INV-2", Counterparty: 3}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	result, err := SearchJournal(MetadataFilter{Counterparty: 3}, &kk)
+	fTest(err, nil)
+	fTest(len(result), 2)
+	fTest(result[0].Reference, "INV-1")
+	fTest(result[1].LinesMetadata[1001].Reference, "INV-2")
+
+	_, err = AddToJournal(AccountingEntry{
+		TimeUnix:      4,
+		DoubleEntry:   DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}},
+		LinesMetadata: map[AccountID]Metadata{3001: {Description: "no line"}},
+	}, &kk)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrMetadataWithoutLine : there is metadata for account ID 3001 but the entry has no line for it"))
+}
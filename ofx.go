@@ -0,0 +1,156 @@
+package accounting
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadOFX reads the bank statement of an OFX or QFX file, both the SGML files of version 1
+// where the elements have no end tags and the XML files of version 2.
+//
+// Every STMTTRN is a transaction with its FITID as its ID, its DTPOSTED as its time, its signed TRNAMT as its amount,
+// its NAME as its payee and its MEMO as its memo. The ACCTID of the statement is its Account, the CURDEF is its Currency
+// and the LEDGERBAL is its ClosingBalance, a balance at a date like 20240310 is at the end of the day.
+// A time without a zone like 20240302 is in UTC, and a zone like [-5:EST] is the offset in hours from UTC.
+//
+// Returns the statement, or an Error with the name ErrInvalidBankStatement if the file is not a statement of one account
+// or a transaction has no valid amount or time.
+func ReadOFX(r io.Reader) (BankStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return BankStatement{}, err
+	}
+
+	// the headers before the OFX element are like OFXHEADER:100 or <?xml version="1.0"?>
+	text := string(data)
+	start := strings.Index(text, "<OFX>")
+	if start < 0 {
+		return BankStatement{}, fErrInvalidBankStatement("it has no OFX element")
+	}
+	text = text[start:]
+
+	var statement BankStatement
+	var current *BankTransaction
+	var hasAmount, hasTime, isLedgerBalance bool
+	for {
+		open := strings.IndexByte(text, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(text[open:], '>')
+		if end < 0 {
+			return BankStatement{}, fErrInvalidBankStatement("its element " + text[open:] + " has no >")
+		}
+		tag := text[open+1 : open+end]
+		text = text[open+end+1:]
+
+		next := strings.IndexByte(text, '<')
+		if next < 0 {
+			next = len(text)
+		}
+		value := html.UnescapeString(strings.TrimSpace(text[:next]))
+
+		switch {
+		case tag == "STMTTRN":
+			current = &BankTransaction{}
+			hasAmount, hasTime = false, false
+		case tag == "/STMTTRN" && current != nil:
+			if !hasAmount || !hasTime {
+				return BankStatement{}, fErrInvalidBankStatement(fmt.Sprintf("the transaction %q has no TRNAMT or no DTPOSTED", current.ID))
+			}
+			statement.Transactions = append(statement.Transactions, *current)
+			current = nil
+		case current == nil:
+			switch tag {
+			case "ACCTID":
+				if statement.Account != "" && statement.Account != value {
+					return BankStatement{}, fErrInvalidBankStatement("it has the statements of more than one account")
+				}
+				statement.Account = value
+			case "CURDEF":
+				statement.Currency = value
+			case "LEDGERBAL", "/LEDGERBAL":
+				isLedgerBalance = tag == "LEDGERBAL"
+			case "BALAMT":
+				if isLedgerBalance {
+					statement.ClosingBalance.Amount, err = parseOFXAmount(value)
+				}
+			case "DTASOF":
+				if isLedgerBalance {
+					statement.ClosingBalance.TimeUnix, err = parseOFXTime(value)
+					if len(value) == len("20060102") {
+						statement.ClosingBalance.TimeUnix = endOfDay(statement.ClosingBalance.TimeUnix)
+					}
+				}
+			}
+			if err != nil {
+				return BankStatement{}, err
+			}
+		case tag == "FITID":
+			current.ID = value
+		case tag == "DTPOSTED":
+			current.TimeUnix, err = parseOFXTime(value)
+			if err != nil {
+				return BankStatement{}, err
+			}
+			hasTime = true
+		case tag == "TRNAMT":
+			amount, err := parseOFXAmount(value)
+			if err != nil {
+				return BankStatement{}, err
+			}
+			current.Amount = amount
+			hasAmount = true
+		case tag == "NAME":
+			current.Payee = value
+		case tag == "MEMO":
+			current.Memo = value
+		}
+	}
+	return statement, nil
+}
+
+// parseOFXAmount parses an amount like -12.50 or -12,50, OFX allows a comma as the decimal point.
+func parseOFXAmount(value string) (Amount, error) {
+	number := strings.ReplaceAll(value, ",", ".")
+	if strings.Contains(value, ".") { // the commas are thousands separators like 1,500.00
+		number = strings.ReplaceAll(value, ",", "")
+	}
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fErrInvalidBankStatement("its amount " + value + " is not a number")
+	}
+	return Amount(amount), nil
+}
+
+// parseOFXTime parses a time like 20240302, 20240302103000 or 20240302103000.000[-5:EST].
+func parseOFXTime(value string) (TimeUnix, error) {
+	value, zone, hasZone := strings.Cut(value, "[")
+	location := time.UTC
+	if hasZone {
+		offset, name, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return 0, fErrInvalidBankStatement("the zone of its time " + value + "[" + zone + " is not an offset in hours")
+		}
+		location = time.FixedZone(name, int(hours*3600))
+	}
+
+	layout := "20060102150405"
+	seconds, _, _ := strings.Cut(value, ".")
+	switch len(seconds) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	}
+	t, err := time.ParseInLocation(layout, value, location)
+	if err != nil {
+		return 0, fErrInvalidBankStatement("its time " + value + " is not a date")
+	}
+	return t.UnixMicro(), nil
+}
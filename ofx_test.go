@@ -0,0 +1,163 @@
+package accounting
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_ReadOFX(t *testing.T) {
+	type input struct {
+		file string
+	}
+	type output struct {
+		BankStatement BankStatement
+		err           error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240310</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><BANKID>121000248<ACCTID>0012345<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST><DTSTART>20240301<DTEND>20240310
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240302103000.000[-5:EST]
+<TRNAMT>1,500.00
+<FITID>F-1
+<NAME>ACME &amp; SONS
+<MEMO>Invoice 17
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240303
+<TRNAMT>-42,50
+<FITID>F-2
+<NAME>Power Co
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1457.50<DTASOF>20240310</LEDGERBAL>
+<AVAILBAL><BALAMT>1400<DTASOF>20240310120000</AVAILBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`,
+			},
+			output: output{
+				BankStatement: BankStatement{
+					Account:        "0012345",
+					Currency:       "USD",
+					ClosingBalance: BankBalance{1710115199999999, 1457.5},
+					Transactions: []BankTransaction{
+						{ID: "F-1", TimeUnix: 1709393400000000, Amount: 1500, Payee: "ACME & SONS", Memo: "Invoice 17"},
+						{ID: "F-2", TimeUnix: 1709424000000000, Amount: -42.5, Payee: "Power Co"},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CURDEF>EUR</CURDEF>
+    <CCACCTFROM><ACCTID>4000</ACCTID></CCACCTFROM>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>202403041200</DTPOSTED>
+        <TRNAMT>-9.99</TRNAMT>
+        <FITID>C-1</FITID>
+        <PAYEE><NAME>Books</NAME></PAYEE>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`,
+			},
+			output: output{
+				BankStatement: BankStatement{
+					Account:  "4000",
+					Currency: "EUR",
+					Transactions: []BankTransaction{
+						{ID: "C-1", TimeUnix: 1709553600000000, Amount: -9.99, Payee: "Books"},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<OFX><STMTTRN><FITID>F-1<DTPOSTED>20240302</STMTTRN></OFX>`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because the transaction \"F-1\" has no TRNAMT or no DTPOSTED"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<OFX><STMTTRN><FITID>F-1<DTPOSTED>2024-03-02<TRNAMT>1</STMTTRN></OFX>`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because its time 2024-03-02 is not a date"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<OFX><STMTTRN><FITID>F-1<DTPOSTED>20240302<TRNAMT>ten</STMTTRN></OFX>`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because its amount ten is not a number"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `<OFX><BANKACCTFROM><ACCTID>1</BANKACCTFROM><BANKACCTFROM><ACCTID>2</BANKACCTFROM></OFX>`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because it has the statements of more than one account"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				file: `NAME,AMOUNT`,
+			},
+			output: output{
+				BankStatement: BankStatement{},
+				err:           fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because it has no OFX element"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.BankStatement, output.err = ReadOFX(strings.NewReader(tt.input.file))
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
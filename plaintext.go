@@ -0,0 +1,150 @@
+package accounting
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PlainTextConfig maps the accounts of the journal to the accounts and the commodities
+// of the plain-text accounting formats like hledger.
+type PlainTextConfig struct {
+	Currency string               // the commodity of the amounts like "USD"
+	Accounts map[AccountID]string // the name of every account like "assets:inventory:widgets"
+
+	// Commodities are the commodities of the quantities of the accounts like "WIDGET".
+	// The quantity of an account with the Currency as its commodity is its amount,
+	// and the quantity of an account without a commodity is always zero.
+	Commodities map[AccountID]string
+}
+
+// textPosting is a line of an entry in a plain-text format.
+type textPosting struct {
+	line         int // the line of the posting in the file, zero if it is written
+	account      string
+	quantity     Quantity // positive for a debit and negative for a credit, zero if the posting has no commodity
+	commodity    string   // the commodity of the quantity, empty if the posting is only an amount of the currency
+	amount       Amount   // positive for a debit and negative for a credit
+	costFlowType string   // the name of the cost flow type, empty if it is defaultCostFlowType
+}
+
+// defaultCostFlowType returns the cost flow type that a posting without a cost flow type gets:
+// INFLOW if it increases the account, NONE if it decreases its quantity or its amount only, otherwise FIFO.
+func defaultCostFlowType(isIncrease bool, quantity Quantity, amount Amount) CostFlowType {
+	switch {
+	case isIncrease:
+		return INFLOW
+	case quantity == 0 || amount == 0:
+		return NONE
+	default:
+		return FIFO
+	}
+}
+
+// toTextPosting returns the posting of the line, it keeps the cost flow type only if it is not the default.
+func toTextPosting(single SingleEntry, config PlainTextConfig) (textPosting, error) {
+	name, ok := config.Accounts[single.AccountID]
+	if !ok {
+		return textPosting{}, newError(ErrPlainTextMapping, "the account ID %v has no name", single.AccountID)
+	}
+
+	p := textPosting{account: name, quantity: single.Quantity, amount: single.Amount}
+	commodity := config.Commodities[single.AccountID]
+	switch {
+	case commodity == "" && single.Quantity != 0:
+		return textPosting{}, newError(ErrPlainTextMapping, "the account ID %v has the quantity %v but no commodity", single.AccountID, single.Quantity)
+	case commodity != "" && commodity == config.Currency && float64(single.Quantity) != float64(single.Amount):
+		return textPosting{}, newError(ErrPlainTextMapping, "the account ID %v has the currency as its commodity but its quantity %v is not its amount %v", single.AccountID, single.Quantity, single.Amount)
+	case commodity == "" || commodity == config.Currency || single.Quantity == 0:
+		p.quantity = 0
+	default:
+		p.commodity = commodity
+	}
+
+	if !GetStatus(single.CostFlowType, single.AccountID) {
+		p.quantity = -p.quantity
+		p.amount = -p.amount
+	}
+
+	isIncrease := single.CostFlowType == INFLOW
+	if single.CostFlowType != defaultCostFlowType(isIncrease, single.Quantity, single.Amount) {
+		var err error
+		p.costFlowType, err = FormatCostFlowType(single.CostFlowType)
+		if err != nil {
+			return textPosting{}, err
+		}
+	}
+	return p, nil
+}
+
+// fromTextPosting returns the line of the posting.
+func fromTextPosting(p textPosting, config PlainTextConfig, accountIDs map[string]AccountID) (SingleEntry, error) {
+	ID, ok := accountIDs[p.account]
+	if !ok {
+		return SingleEntry{}, newError(ErrPlainTextMapping, "the account %v on line %v has no account ID", p.account, p.line)
+	}
+
+	single := SingleEntry{AccountID: ID, Amount: Amount(math.Abs(float64(p.amount)))}
+	isDebit := p.amount > 0
+	switch {
+	case p.commodity != "":
+		if config.Commodities[ID] != p.commodity {
+			return SingleEntry{}, newError(ErrPlainTextMapping, "the commodity %v on line %v is not the commodity of the account %v", p.commodity, p.line, p.account)
+		}
+		single.Quantity = Quantity(math.Abs(float64(p.quantity)))
+		isDebit = p.quantity > 0
+	case config.Commodities[ID] == config.Currency:
+		single.Quantity = Quantity(single.Amount)
+	}
+
+	isIncrease := isDebit == bool(IsNatureDebit(ID))
+	single.CostFlowType = defaultCostFlowType(isIncrease, single.Quantity, single.Amount)
+	if p.costFlowType != "" {
+		var err error
+		single.CostFlowType, err = ParseCostFlowType(p.costFlowType)
+		if err != nil {
+			return SingleEntry{}, err
+		}
+		if (single.CostFlowType == INFLOW) != isIncrease {
+			return SingleEntry{}, newError(ErrPlainTextMapping, "the cost flow type %v on line %v is not the direction of the posting", p.costFlowType, p.line)
+		}
+	}
+	return single, nil
+}
+
+// keepLots keeps the lots of the lines at their indexes as the Layers of the entry only if their cost flow types
+// would not move them, a line that takes out other lots is NONE. The inventories are the inventories just before the entry.
+func keepLots(entry *AccountingEntry, indexes map[AccountID]int, lots AccountIDAndInventory, IDAndInventory AccountIDAndInventory) {
+	layers := make(AccountIDAndInventory)
+	for ID, lot := range lots {
+		single := &entry.DoubleEntry[indexes[ID]]
+		if !slices.Equal(movedLayers(*entry, *single, IDAndInventory[ID]), lot) {
+			layers[ID] = lot
+			if single.CostFlowType != INFLOW {
+				single.CostFlowType = NONE
+			}
+		}
+	}
+	if len(layers) != 0 {
+		entry.Layers = layers
+	}
+}
+
+// reverseAccounts returns the account IDs by their names.
+func reverseAccounts(config PlainTextConfig) map[string]AccountID {
+	accountIDs := make(map[string]AccountID, len(config.Accounts))
+	for ID, name := range config.Accounts {
+		accountIDs[name] = ID
+	}
+	return accountIDs
+}
+
+func formatTextNumber[t ~float64](value t) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 64)
+}
+
+// parseTextNumber parses a number like -1,234.5, the commas are thousands separators.
+func parseTextNumber(value string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+}
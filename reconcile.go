@@ -0,0 +1,208 @@
+package accounting
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// JournalLine is a line of an entry of the journal.
+type JournalLine struct {
+	EntryID
+	LineIndex int // the index of the line in the DoubleEntry of the entry
+}
+
+// LineReconciliation is the reconciliation state of a line of the journal.
+type LineReconciliation struct {
+	BankTransactionID string   // the ID of the bank transaction that the line is matched with
+	BankAmount        Amount   // the amount of the bank transaction, it can differ from the line within the tolerance
+	TimeUnix          TimeUnix // the time of the closing balance of the statement that reconciled the line
+}
+
+// ReconciliationStore is the storage of the reconciliation states of the lines.
+// GetLineReconciliation returns the state that SetLineReconciliation stored for the line and false if the line is not reconciled.
+type ReconciliationStore interface {
+	GetLineReconciliation(JournalLine) (LineReconciliation, bool, error)
+	SetLineReconciliation(JournalLine, LineReconciliation) error
+}
+
+// ReconciliationConfig configures the matching of the lines of an account with the transactions of its bank statement.
+type ReconciliationConfig struct {
+	AccountID       AccountID // the cash account of the bank account
+	AmountTolerance Amount    // the biggest difference of the amounts of a match
+	TimeTolerance   TimeUnix  // the biggest difference of the times of a match in microseconds, like a few days
+}
+
+// BookLine is a line of the account in the journal.
+type BookLine struct {
+	JournalLine
+	TimeUnix    TimeUnix
+	Amount      Amount // positive for a debit like money into the bank account and negative for a credit
+	Reference   string // the Reference of the entry
+	Description string // the Description of the entry
+}
+
+// ReconciliationMatch is a line of the journal matched with a bank transaction.
+type ReconciliationMatch struct {
+	Book BookLine
+	Bank BankTransaction
+}
+
+// ReconciliationReport is the reconciliation of an account with its bank statement at the time of its closing balance.
+//
+// The adjusted balances tie when the books and the bank agree:
+// the AdjustedBankBalance is the BankBalance with the lines that the bank doesn't have yet, like deposits in transit
+// and outstanding checks, and the AdjustedBookBalance is the BookBalance with the bank transactions that the books
+// don't have yet, like bank fees, and the differences of the amounts of the matches and of the reconciled lines.
+type ReconciliationReport struct {
+	AccountID
+	TimeUnix                                        // the time of the closing balance of the statement
+	Matches                   []ReconciliationMatch // the new matches, record them with RecordReconciliation
+	UnmatchedBookLines        []BookLine            // the lines that are not reconciled and have no match
+	UnmatchedBankTransactions []BankTransaction     // the transactions that are not reconciled and have no match
+	BookBalance               Amount                // the balance of the account in the journal at the time
+	BankBalance               Amount                // the closing balance of the statement
+	AdjustedBookBalance       Amount
+	AdjustedBankBalance       Amount
+	Difference                Amount // AdjustedBankBalance - AdjustedBookBalance
+	IsTied                    bool   // true if the Difference is less than a millionth
+}
+
+// Reconcile matches the lines of the account in the journal with the transactions of its bank statement
+// and returns the report that shows whether the book balance ties to the bank balance. Nothing is written,
+// review the report and record its matches with RecordReconciliation.
+//
+// Parameters:
+//   - dbCommand: The storage of the journal
+//   - store: The storage of the reconciliation states of the lines
+//   - statement: The bank statement, it should have a ClosingBalance
+//   - config: The account and the tolerances of the matching
+//
+// Only the lines until the time of the closing balance are reconciled. The lines and the bank transactions that
+// are already reconciled, by an earlier statement or by hand with SetLineReconciliation, are not matched again.
+// A bank transaction is matched with the line that has its amount and its time within the tolerances,
+// the line with the bank transaction ID as its Reference first, then the nearest one in time and then in amount.
+// The transactions are matched in the order of their times.
+//
+// Returns the report, or an Error with the name ErrInvalidBankStatement if the statement has no closing balance.
+func Reconcile(dbCommand DB, store ReconciliationStore, statement BankStatement, config ReconciliationConfig) (ReconciliationReport, error) {
+	closing := statement.ClosingBalance
+	if closing.TimeUnix == 0 {
+		return ReconciliationReport{}, fErrInvalidBankStatement("it has no closing balance to reconcile with")
+	}
+
+	report := ReconciliationReport{AccountID: config.AccountID, TimeUnix: closing.TimeUnix, BankBalance: closing.Amount}
+	reconciledIDs := make(map[string]bool)
+	var lines []BookLine
+	for entry, err := range dbCommand.Journal(JournalRange{To: closing.TimeUnix, AccountIDs: []AccountID{config.AccountID}}) {
+		if err != nil {
+			return ReconciliationReport{}, err
+		}
+
+		for i, single := range entry.DoubleEntry {
+			if single.AccountID != config.AccountID {
+				continue
+			}
+
+			line := BookLine{JournalLine{entry.ID, i}, entry.TimeUnix, single.Amount, entry.Reference, entry.Description}
+			if !GetStatus(single.CostFlowType, single.AccountID) {
+				line.Amount = -line.Amount
+			}
+			report.BookBalance += line.Amount
+
+			state, isReconciled, err := store.GetLineReconciliation(line.JournalLine)
+			if err != nil {
+				return ReconciliationReport{}, err
+			}
+			if isReconciled {
+				reconciledIDs[state.BankTransactionID] = true
+				report.AdjustedBookBalance += state.BankAmount - line.Amount
+				continue
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	transactions := slices.Clone(statement.Transactions)
+	slices.SortStableFunc(transactions, func(a, b BankTransaction) int {
+		return cmp.Compare(a.TimeUnix, b.TimeUnix)
+	})
+
+	isMatched := make([]bool, len(lines))
+	for _, transaction := range transactions {
+		if transaction.ID != "" && reconciledIDs[transaction.ID] {
+			continue
+		}
+
+		best := -1
+		for i, line := range lines {
+			if isMatched[i] || !isWithinTolerance(line, transaction, config) {
+				continue
+			}
+			if best == -1 || isBetterMatch(line, lines[best], transaction) {
+				best = i
+			}
+		}
+
+		if best == -1 {
+			report.UnmatchedBankTransactions = append(report.UnmatchedBankTransactions, transaction)
+			report.AdjustedBookBalance += transaction.Amount
+			continue
+		}
+		isMatched[best] = true
+		report.Matches = append(report.Matches, ReconciliationMatch{lines[best], transaction})
+		report.AdjustedBookBalance += transaction.Amount - lines[best].Amount
+	}
+
+	for i, line := range lines {
+		if !isMatched[i] {
+			report.UnmatchedBookLines = append(report.UnmatchedBookLines, line)
+			report.AdjustedBankBalance += line.Amount
+		}
+	}
+
+	report.AdjustedBookBalance += report.BookBalance
+	report.AdjustedBankBalance += report.BankBalance
+	report.Difference = report.AdjustedBankBalance - report.AdjustedBookBalance
+	report.IsTied = math.Abs(float64(report.Difference)) < 1e-6
+	return report, nil
+}
+
+func isWithinTolerance(line BookLine, transaction BankTransaction, config ReconciliationConfig) bool {
+	return math.Abs(float64(line.Amount-transaction.Amount)) <= float64(config.AmountTolerance) &&
+		timeDistance(line.TimeUnix, transaction.TimeUnix) <= config.TimeTolerance
+}
+
+// isBetterMatch reports whether the line a is a better match of the transaction than the line b.
+func isBetterMatch(a, b BookLine, transaction BankTransaction) bool {
+	isReferenceA := transaction.ID != "" && a.Reference == transaction.ID
+	isReferenceB := transaction.ID != "" && b.Reference == transaction.ID
+	if isReferenceA != isReferenceB {
+		return isReferenceA
+	}
+
+	timeA, timeB := timeDistance(a.TimeUnix, transaction.TimeUnix), timeDistance(b.TimeUnix, transaction.TimeUnix)
+	if timeA != timeB {
+		return timeA < timeB
+	}
+	return math.Abs(float64(a.Amount-transaction.Amount)) < math.Abs(float64(b.Amount-transaction.Amount))
+}
+
+func timeDistance(a, b TimeUnix) TimeUnix {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
+
+// RecordReconciliation stores the matches of the report as the reconciliation states of their lines,
+// so the next reconciliation doesn't match them again.
+func RecordReconciliation(store ReconciliationStore, report ReconciliationReport) error {
+	for _, match := range report.Matches {
+		err := store.SetLineReconciliation(match.Book.JournalLine, LineReconciliation{match.Bank.ID, match.Bank.Amount, report.TimeUnix})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
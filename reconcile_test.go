@@ -0,0 +1,86 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+)
+
+type myReconciliationStore map[JournalLine]LineReconciliation
+
+func (s myReconciliationStore) GetLineReconciliation(line JournalLine) (LineReconciliation, bool, error) {
+	state, ok := s[line]
+	return state, ok, nil
+}
+func (s myReconciliationStore) SetLineReconciliation(line JournalLine, state LineReconciliation) error {
+	s[line] = state
+	return nil
+}
+
+func Test_Reconcile(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+	entries := []AccountingEntry{
+		{TimeUnix: 1709251200000000, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}, Metadata: Metadata{Reference: "CAP"}},
+		{TimeUnix: 1709337600000000, DoubleEntry: DoubleEntry{{INFLOW, 2001, 1500, 1500}, {INFLOW, -4001, 0, 1500}}, Metadata: Metadata{Reference: "INV-17"}},
+		{TimeUnix: 1709424000000000, DoubleEntry: DoubleEntry{{INFLOW, 2001, 1500, 1500}, {INFLOW, -4001, 0, 1500}}, Metadata: Metadata{Reference: "INV-18"}},
+		{TimeUnix: 1709424000000000, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 42.5}, {FIFO, 2001, 42.5, 42.5}}, Metadata: Metadata{Description: "power"}},
+		{TimeUnix: 1709596800000000, DoubleEntry: DoubleEntry{{INFLOW, 3001, 0, 200}, {FIFO, 2001, 200, 200}}, Metadata: Metadata{Reference: "CHECK-9"}},
+		{TimeUnix: 1710201600000000, DoubleEntry: DoubleEntry{{INFLOW, 2001, 50, 50}, {INFLOW, -4001, 0, 50}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	// the capital was reconciled with the statement before
+	store := myReconciliationStore{{1, 1}: {"B-0", 1000, 1709337599999999}}
+	statement := BankStatement{
+		ClosingBalance: BankBalance{1710115199999999, 2442.75},
+		Transactions: []BankTransaction{
+			{ID: "FEE", TimeUnix: 1710028800000000, Amount: -15, Memo: "account fee"},
+			{ID: "INV-17", TimeUnix: 1709510400000000, Amount: 1500, Payee: "ACME"},
+			{ID: "F-2", TimeUnix: 1709424000000000, Amount: -42.25, Payee: "Power Co"},
+			{ID: "B-0", TimeUnix: 1709251200000000, Amount: 1000},
+		},
+	}
+	config := ReconciliationConfig{AccountID: 2001, AmountTolerance: 0.5, TimeTolerance: 3 * 24 * 60 * 60 * 1e6}
+
+	report, err := Reconcile(&kk, store, statement, config)
+	fTest(err, nil)
+	fTest(report, ReconciliationReport{
+		AccountID: 2001,
+		TimeUnix:  1710115199999999,
+		Matches: []ReconciliationMatch{
+			{BookLine{JournalLine{4, 1}, 1709424000000000, -42.5, "", "power"}, statement.Transactions[2]},
+			{BookLine{JournalLine{2, 0}, 1709337600000000, 1500, "INV-17", ""}, statement.Transactions[1]},
+		},
+		UnmatchedBookLines: []BookLine{
+			{JournalLine{3, 0}, 1709424000000000, 1500, "INV-18", ""},
+			{JournalLine{5, 1}, 1709596800000000, -200, "CHECK-9", ""},
+		},
+		UnmatchedBankTransactions: []BankTransaction{statement.Transactions[0]},
+		BookBalance:               3757.5,
+		BankBalance:               2442.75,
+		AdjustedBookBalance:       3742.75,
+		AdjustedBankBalance:       3742.75,
+		Difference:                0,
+		IsTied:                    true,
+	})
+
+	// the recorded matches are not matched again
+	fTest(RecordReconciliation(store, report), nil)
+	fTest(store[JournalLine{2, 0}], LineReconciliation{"INV-17", 1500, 1710115199999999})
+	again, err := Reconcile(&kk, store, statement, config)
+	fTest(err, nil)
+	fTest(again.Matches, nil)
+	fTest(again.UnmatchedBookLines, report.UnmatchedBookLines)
+	fTest(again.UnmatchedBankTransactions, report.UnmatchedBankTransactions)
+	fTest(again.AdjustedBookBalance, report.AdjustedBookBalance)
+	fTest(again.IsTied, true)
+
+	statement.ClosingBalance = BankBalance{}
+	_, err = Reconcile(&kk, store, statement, config)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrInvalidBankStatement : the bank statement is invalid because it has no closing balance to reconcile with"))
+}
@@ -0,0 +1,150 @@
+package accounting
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+)
+
+// ReportFilter selects the lines that go into a report. A zero field selects every line.
+type ReportFilter struct {
+	From       TimeUnix       // the first time of the period
+	To         TimeUnix       // the last time of the period
+	Dimensions Dimensions     // the values that the dimensions of the line should have
+	Metadata   MetadataFilter // the metadata of the entry of the line
+
+	ExcludeReversals bool // leave out the entries that are reversed and the entries that reverse them
+}
+
+// TrialBalanceRow is the total of the lines of an account for one value of the pivot dimension.
+type TrialBalanceRow struct {
+	AccountID
+	DimensionValue string // the value of the pivot dimension, empty if there is no pivot or the lines have no value
+	Debit          Amount
+	Credit         Amount
+}
+
+// IncomeStatementRow is the result of the period for one value of the pivot dimension.
+type IncomeStatementRow struct {
+	DimensionValue string // the value of the pivot dimension, empty if there is no pivot or the lines have no value
+	Revenues       Amount // the credits minus the debits of the credit nature accounts
+	Expenses       Amount // the debits minus the credits of the debit nature accounts
+	NetIncome      Amount // the revenues minus the expenses
+}
+
+// reportLine is a line of the journal with the dimensions it has in its entry.
+type reportLine struct {
+	SingleEntry
+	Dimensions
+	IsDebit
+}
+
+// getReportLines returns the lines of the journal that the filter selects.
+func getReportLines(filter ReportFilter, dbCommand DB, isEntryIncluded func(AccountingEntry) bool) ([]reportLine, error) {
+	journal, err := getTheJournal(dbCommand, JournalRange{From: filter.From, To: filter.To})
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []reportLine
+	for _, entry := range journal {
+		isReversal := entry.ReversedBy != 0 || entry.ReversalOf != 0
+		if !isEntryIncluded(entry) || !MatchMetadata(filter.Metadata, entry) || filter.ExcludeReversals && isReversal {
+			continue
+		}
+
+		for _, single := range entry.DoubleEntry {
+			dimensions := GetLineDimensions(entry, single.AccountID)
+			if !matchDimensions(filter.Dimensions, dimensions) {
+				continue
+			}
+			lines = append(lines, reportLine{single, dimensions, GetStatus(single.CostFlowType, single.AccountID)})
+		}
+	}
+	return lines, nil
+}
+
+// TrialBalance returns the debit and credit totals of every account in the lines that the filter selects.
+// If pivot is the name of a dimension then every account has a row for each value of this dimension.
+// The rows are sorted by account ID and then by the value of the dimension.
+func TrialBalance(pivot string, filter ReportFilter, dbCommand DB) ([]TrialBalanceRow, error) {
+	lines, err := getReportLines(filter, dbCommand, func(AccountingEntry) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		AccountID
+		DimensionValue string
+	}
+	rows := make(map[key]*TrialBalanceRow)
+	for _, line := range lines {
+		k := key{line.AccountID, line.Dimensions[pivot]}
+		row, ok := rows[k]
+		if !ok {
+			row = &TrialBalanceRow{AccountID: k.AccountID, DimensionValue: k.DimensionValue}
+			rows[k] = row
+		}
+
+		if line.IsDebit {
+			row.Debit += line.Amount
+		} else {
+			row.Credit += line.Amount
+		}
+	}
+
+	var result []TrialBalanceRow
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	slices.SortFunc(result, func(a, b TrialBalanceRow) int {
+		return cmp.Or(cmp.Compare(a.AccountID, b.AccountID), cmp.Compare(a.DimensionValue, b.DimensionValue))
+	})
+	return result, nil
+}
+
+// IncomeStatement returns the revenues, the expenses and the net income of the lines of the
+// revenue and expense accounts that the filter selects.
+// The closing entries made by MakeClosingEntry are left out so a closed period still shows its result.
+// If pivot is the name of a dimension then there is a row for each value of this dimension,
+// sorted by the value.
+func IncomeStatement(revenueAndExpenseAccounts []AccountID, pivot string, filter ReportFilter, dbCommand DB) ([]IncomeStatementRow, error) {
+	lines, err := getReportLines(filter, dbCommand, func(entry AccountingEntry) bool {
+		return !entry.IsClosing
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[string]*IncomeStatementRow)
+	for _, line := range lines {
+		if !slices.Contains(revenueAndExpenseAccounts, line.AccountID) {
+			continue
+		}
+
+		value := line.Dimensions[pivot]
+		row, ok := rows[value]
+		if !ok {
+			row = &IncomeStatementRow{DimensionValue: value}
+			rows[value] = row
+		}
+
+		// a line on the other side of the nature of its account decreases the account
+		amount := line.Amount
+		if line.IsDebit != IsNatureDebit(line.AccountID) {
+			amount = -amount
+		}
+		if IsNatureDebit(line.AccountID) {
+			row.Expenses += amount
+		} else {
+			row.Revenues += amount
+		}
+		row.NetIncome = row.Revenues - row.Expenses
+	}
+
+	var result []IncomeStatementRow
+	for _, value := range slices.Sorted(maps.Keys(rows)) {
+		result = append(result, *rows[value])
+	}
+	return result, nil
+}
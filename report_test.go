@@ -0,0 +1,113 @@
+package accounting
+
+import (
+	"testing"
+)
+
+func newReportTestDB() *myDB {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 20, 200}, {WAC, 2001, 200, 200}}},
+		{
+			TimeUnix:    3,
+			DoubleEntry: DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, 3001, 5, 50}, {INFLOW, 2001, 80, 80}, {INFLOW, -4001, 5, 80}},
+			Metadata:    Metadata{Dimensions: Dimensions{"project": "A"}},
+		},
+		{
+			TimeUnix:      4,
+			DoubleEntry:   DoubleEntry{{FIFO, 1001, 5, 50}, {INFLOW, 3001, 5, 50}, {INFLOW, 2001, 70, 70}, {INFLOW, -4001, 5, 70}},
+			Metadata:      Metadata{Dimensions: Dimensions{"project": "B"}},
+			LinesMetadata: map[AccountID]Metadata{-4001: {Dimensions: Dimensions{"region": "north"}}},
+		},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	_, _, err := CloseFiscalYear(5, -3000, 3900, []AccountID{-4001, 3001}, &kk)
+	fTest(err, nil)
+	return &kk
+}
+
+func Test_TrialBalance(t *testing.T) {
+	kk := newReportTestDB()
+
+	rows, err := TrialBalance("project", ReportFilter{Dimensions: Dimensions{"project": "A"}}, kk)
+	fTest(err, nil)
+	fTest(rows, []TrialBalanceRow{
+		{-4001, "A", 0, 80},
+		{1001, "A", 0, 50},
+		{2001, "A", 80, 0},
+		{3001, "A", 50, 0},
+	})
+
+	rows, err = TrialBalance("region", ReportFilter{From: 3, To: 4}, kk)
+	fTest(err, nil)
+	fTest(rows, []TrialBalanceRow{
+		{-4001, "", 0, 80},
+		{-4001, "north", 0, 70},
+		{1001, "", 0, 100},
+		{2001, "", 150, 0},
+		{3001, "", 100, 0},
+	})
+
+	rows, err = TrialBalance("", ReportFilter{From: 5}, kk)
+	fTest(err, nil)
+	fTest(rows, []TrialBalanceRow{
+		{-4001, "", 150, 0},
+		{-3000, "", 0, 50},
+		{3001, "", 0, 100},
+	})
+
+	ID, err := AddToJournal(AccountingEntry{TimeUnix: 6, DoubleEntry: DoubleEntry{{INFLOW, 1001, 1, 10}, {WAC, 2001, 10, 10}}}, kk)
+	fTest(err, nil)
+	_, err = ReverseEntry(kk, ID, 7)
+	fTest(err, nil)
+
+	rows, err = TrialBalance("", ReportFilter{From: 6}, kk)
+	fTest(err, nil)
+	fTest(rows, []TrialBalanceRow{
+		{1001, "", 10, 10},
+		{2001, "", 10, 10},
+	})
+
+	rows, err = TrialBalance("", ReportFilter{From: 6, ExcludeReversals: true}, kk)
+	fTest(err, nil)
+	fTest(rows, nil)
+}
+
+func Test_IncomeStatement(t *testing.T) {
+	kk := newReportTestDB()
+	accounts := []AccountID{-4001, 3001}
+
+	rows, err := IncomeStatement(accounts, "project", ReportFilter{}, kk)
+	fTest(err, nil)
+	fTest(rows, []IncomeStatementRow{
+		{"A", 80, 50, 30},
+		{"B", 70, 50, 20},
+	})
+
+	rows, err = IncomeStatement(accounts, "", ReportFilter{}, kk)
+	fTest(err, nil)
+	fTest(rows, []IncomeStatementRow{
+		{"", 150, 100, 50},
+	})
+
+	rows, err = IncomeStatement(accounts, "", ReportFilter{Metadata: MetadataFilter{Text: "closing entry"}}, kk)
+	fTest(err, nil)
+	fTest(rows, nil)
+
+	// the reversal of the closing entry is a closing entry too
+	reversal, err := ReverseEntry(kk, kk.lastEntry.ID, 6)
+	fTest(err, nil)
+	fTest(reversal.IsClosing, true)
+	rows, err = IncomeStatement(accounts, "", ReportFilter{}, kk)
+	fTest(err, nil)
+	fTest(rows, []IncomeStatementRow{
+		{"", 150, 100, 50},
+	})
+}
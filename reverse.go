@@ -0,0 +1,190 @@
+package accounting
+
+import (
+	"maps"
+	"slices"
+)
+
+// ReversalDB is a DB that can mark a posted entry as reversed.
+// SetEntryReversedBy marks the entry with the first ID as reversed by the entry with the second ID.
+type ReversalDB interface {
+	DB
+	SetEntryReversedBy(EntryID, EntryID) error
+}
+
+// ReverseEntry adds to the journal the exact mirror of the entry with entryID and marks the original as reversed.
+//
+// Parameters:
+//   - dbCommand: The storage of the journal and the inventories
+//   - entryID: The ID of the entry to reverse, as returned by AddToJournal
+//   - at: The time of the reversing entry, it should not be before the time of the last entry
+//
+// Every line of the original is mirrored with the same quantity and amount in the other direction.
+// The cost layers that a FIFO, LIFO, HIFO or LOFO line consumed are put back with their original times,
+// and the layer that an INFLOW line added is taken out exactly if it is still in the inventory.
+// The layers are found by replaying the journal, and they are kept in the Layers of the reversing entry
+// so that CheckAllTheJournal gives the same inventories.
+//
+// The reversing entry has the dimensions of the original and of its lines.
+// It has ReversalOf set to the original, and the original gets ReversedBy
+// set to the reversing entry through SetEntryReversedBy, so reports can exclude both entries or show them.
+//
+// Returns the reversing entry, or an error if the entry is not found, is already reversed or can't be reversed,
+// or an Error with the name ErrUnsupportedByDB if dbCommand is not a ReversalDB.
+func ReverseEntry(dbCommand DB, entryID EntryID, at TimeUnix) (AccountingEntry, error) {
+	reversalDB, ok := dbCommand.(ReversalDB)
+	if !ok {
+		return AccountingEntry{}, fErrUnsupportedByDB("mark an entry as reversed", "ReversalDB")
+	}
+
+	original, isFound, err := dbCommand.GetEntry(entryID)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+
+	if !isFound {
+		return AccountingEntry{}, fErrEntryNotFound(entryID)
+	}
+
+	if original.ReversedBy != 0 {
+		return AccountingEntry{}, newError(ErrEntryIsAlreadyReversed, "the entry with ID %v is already reversed by the entry with ID %v", entryID, original.ReversedBy)
+	}
+
+	before, after, err := replayTheJournalAround(entryID, dbCommand)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+
+	reversal, err := addToJournal(makeReversingEntry(at, original, before, after), dbCommand)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+
+	err = reversalDB.SetEntryReversedBy(entryID, reversal.ID)
+	if err != nil {
+		return AccountingEntry{}, err
+	}
+
+	return reversal, nil
+}
+
+// replayTheJournalAround replays the whole journal and returns the inventories of the accounts
+// of the entry with entryID just before it and the inventories of all the accounts after the last entry.
+func replayTheJournalAround(entryID EntryID, dbCommand DB) (AccountIDAndInventory, AccountIDAndInventory, error) {
+	var isFound bool
+	before := make(AccountIDAndInventory)
+
+	var lastEntry AccountingEntry
+	IDAndInventory := make(AccountIDAndInventory)
+	for entry, err := range dbCommand.Journal(JournalRange{}) {
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if entry.ID == entryID {
+			isFound = true
+			for _, single := range entry.DoubleEntry {
+				before[single.AccountID] = slices.Clone(IDAndInventory[single.AccountID])
+			}
+		}
+
+		IDAndInventory, err = CheckAndProcessDoubleEntry(lastEntry.TimeUnix, entry, IDAndInventory)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lastEntry = entry
+	}
+
+	if !isFound {
+		return nil, nil, fErrEntryNotFound(entryID)
+	}
+
+	return before, IDAndInventory, nil
+}
+
+func makeReversingEntry(at TimeUnix, original AccountingEntry, before AccountIDAndInventory, after AccountIDAndInventory) AccountingEntry {
+	// the reversal has the dimensions of the original so it is in the same reports and has the required dimensions,
+	// and the reversal of a closing entry is a closing entry
+	reversal := AccountingEntry{
+		TimeUnix:   at,
+		ReversalOf: original.ID,
+		IsClosing:  original.IsClosing,
+		Metadata:   Metadata{Dimensions: maps.Clone(original.Dimensions)},
+	}
+	for ID, m := range original.LinesMetadata {
+		if len(m.Dimensions) == 0 {
+			continue
+		}
+		if reversal.LinesMetadata == nil {
+			reversal.LinesMetadata = make(map[AccountID]Metadata)
+		}
+		reversal.LinesMetadata[ID] = Metadata{Dimensions: maps.Clone(m.Dimensions)}
+	}
+
+	for _, single := range original.DoubleEntry {
+		ID := single.AccountID
+		layers := movedLayers(original, single, before[ID])
+
+		mirror := SingleEntry{INFLOW, ID, single.Quantity, single.Amount}
+		if single.CostFlowType == INFLOW {
+			// a NONE line with layers takes out exactly the added layer, but it could be merged or consumed
+			// by later entries, then we just take out the totals
+			mirror.CostFlowType = NONE
+			if layers != nil {
+				if _, err := processLayers(mirror, layers, after[ID]); err != nil {
+					layers = nil
+				}
+			}
+		}
+
+		if layers != nil {
+			if reversal.Layers == nil {
+				reversal.Layers = make(AccountIDAndInventory)
+			}
+			reversal.Layers[ID] = layers
+		}
+		reversal.DoubleEntry = append(reversal.DoubleEntry, mirror)
+	}
+
+	return reversal
+}
+
+// movedLayers returns the cost layers that the line added to or took out of the inventory,
+// or nil if the line merged the inventory into one layer.
+func movedLayers(entry AccountingEntry, single SingleEntry, inventoryBefore Inventory) Inventory {
+	if layers := entry.Layers[single.AccountID]; layers != nil {
+		return slices.Clone(layers)
+	}
+
+	if single.Quantity == 0 || single.Amount == 0 {
+		return nil
+	}
+
+	switch single.CostFlowType {
+	case INFLOW:
+		return Inventory{{entry.TimeUnix, single.Quantity, single.Amount}}
+	case FIFO, LIFO, HIFO, LOFO:
+		inventoryBefore = slices.Clone(inventoryBefore)
+		sortInventoryByCostFlow(single.CostFlowType, inventoryBefore)
+		return takeLayers(single.Quantity, inventoryBefore)
+	}
+	return nil
+}
+
+// takeLayers returns the parts of the layers that decreaseInventory takes out for the quantity.
+func takeLayers(qty Quantity, inventoryVariable Inventory) Inventory {
+	var taken Inventory
+	remainingQty := qty
+	for _, record := range inventoryVariable {
+		if record.Quantity <= remainingQty {
+			remainingQty -= record.Quantity
+			taken = append(taken, record)
+		} else if remainingQty > 0 {
+			price := float64(record.Amount) / float64(record.Quantity)
+			taken = append(taken, InventoryRecord{record.TimeUnix, remainingQty, Amount(float64(remainingQty) * price)})
+			remainingQty = 0
+		}
+	}
+	return taken
+}
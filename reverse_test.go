@@ -0,0 +1,290 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_takeLayers(t *testing.T) {
+	type input struct {
+		Quantity  Quantity
+		Inventory Inventory
+	}
+	type output struct {
+		Inventory Inventory
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				Quantity:  15,
+				Inventory: Inventory{{1, 10, 100}, {2, 10, 200}},
+			},
+			output: output{
+				Inventory: Inventory{{1, 10, 100}, {2, 5, 100}},
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				Quantity:  10,
+				Inventory: Inventory{{1, 10, 100}, {2, 10, 200}},
+			},
+			output: output{
+				Inventory: Inventory{{1, 10, 100}},
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				Quantity:  5,
+				Inventory: Inventory{{2, 10, 200}, {1, 10, 100}},
+			},
+			output: output{
+				Inventory: Inventory{{2, 5, 100}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.Inventory = takeLayers(tt.input.Quantity, tt.input.Inventory)
+
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_processLayers(t *testing.T) {
+	type input struct {
+		SingleEntry SingleEntry
+		Layers      Inventory
+		Inventory   Inventory
+	}
+	type output struct {
+		Inventory Inventory
+		err       error
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{INFLOW, 1, 15, 200},
+				Layers:      Inventory{{1, 10, 100}, {2, 5, 100}},
+				Inventory:   Inventory{{2, 5, 100}},
+			},
+			output: output{
+				Inventory: Inventory{{1, 10, 100}, {2, 5, 100}, {2, 5, 100}},
+				err:       nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{NONE, 1, 5, 100},
+				Layers:      Inventory{{2, 5, 100}},
+				Inventory:   Inventory{{1, 10, 100}, {2, 10, 200}},
+			},
+			output: output{
+				Inventory: Inventory{{1, 10, 100}, {2, 5, 100}},
+				err:       nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{FIFO, 1, 5, 100},
+				Layers:      Inventory{{2, 5, 100}},
+				Inventory:   Inventory{{1, 10, 100}, {2, 10, 200}},
+			},
+			output: output{
+				Inventory: nil,
+				err:       fmt.Errorf("ErrLayersNotByCostFlowType : the layers of account ID 1 are not the layers that its cost flow type 2 takes out, use the cost flow type NONE to take out other layers"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{LIFO, 1, 15, 250},
+				Layers:      Inventory{{2, 10, 200}, {1, 5, 50}},
+				Inventory:   Inventory{{1, 10, 100}, {2, 10, 200}},
+			},
+			output: output{
+				Inventory: Inventory{{1, 5, 50}, {2, 0, 0}},
+				err:       nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{NONE, 1, 10, 300},
+				Layers:      Inventory{{5, 10, 300}},
+				Inventory:   Inventory{{5, 10, 100}, {5, 10, 300}},
+			},
+			output: output{
+				Inventory: Inventory{{5, 10, 100}, {5, 0, 0}},
+				err:       nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{NONE, 1, 15, 150},
+				Layers:      Inventory{{5, 15, 150}},
+				Inventory:   Inventory{{5, 4, 40}, {5, 10, 300}, {5, 20, 200}},
+			},
+			output: output{
+				Inventory: Inventory{{5, 0, 0}, {5, 10, 300}, {5, 9, 90}},
+				err:       nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{NONE, 1, 10, 200},
+				Layers:      Inventory{{5, 10, 200}},
+				Inventory:   Inventory{{5, 10, 100}, {5, 10, 300}},
+			},
+			output: output{
+				Inventory: nil,
+				err:       fmt.Errorf("ErrLayerNotFoundInInventory : the layer {5 10 200} of account ID 1 is not found in the inventory"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{NONE, 1, 5, 100},
+				Layers:      Inventory{{3, 5, 100}},
+				Inventory:   Inventory{{1, 10, 100}, {2, 10, 200}},
+			},
+			output: output{
+				Inventory: nil,
+				err:       fmt.Errorf("ErrLayerNotFoundInInventory : the layer {3 5 100} of account ID 1 is not found in the inventory"),
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				SingleEntry: SingleEntry{INFLOW, 1, 5, 90},
+				Layers:      Inventory{{3, 5, 100}},
+				Inventory:   nil,
+			},
+			output: output{
+				Inventory: nil,
+				err:       fmt.Errorf("ErrLayersMismatch : the layers of account ID 1 add up to quantity = 5 and amount = 100 but the line has quantity = 5 and amount = 90"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		output.Inventory, output.err = processLayers(tt.input.SingleEntry, tt.input.Layers, tt.input.Inventory)
+		output.err = goerrors.NormalizeTheError(output.err)
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
+
+func Test_ReverseEntry(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 2, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+		{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 200}, {WAC, 2001, 200, 200}}},
+		{TimeUnix: 4, DoubleEntry: DoubleEntry{{FIFO, 1001, 15, 200}, {INFLOW, 3001, 15, 200}, {INFLOW, 2001, 300, 300}, {INFLOW, -4001, 15, 300}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+	fTest(kk.myInv[1001], Inventory{{3, 5, 100}})
+
+	reversal, err := ReverseEntry(&kk, 4, 5)
+	fTest(err, nil)
+	fTest(reversal, AccountingEntry{
+		ID:       5,
+		TimeUnix: 5,
+		DoubleEntry: DoubleEntry{
+			{INFLOW, 1001, 15, 200},
+			{NONE, 3001, 15, 200},
+			{NONE, 2001, 300, 300},
+			{NONE, -4001, 15, 300},
+		},
+		ReversalOf: 4,
+		Layers: AccountIDAndInventory{
+			1001:  Inventory{{2, 10, 100}, {3, 5, 100}},
+			3001:  Inventory{{4, 15, 200}},
+			2001:  Inventory{{4, 300, 300}},
+			-4001: Inventory{{4, 15, 300}},
+		},
+		PreviousHash: kk.myEntries[3].Hash,
+		Hash:         reversal.Hash,
+	})
+	fTest(reversal.Hash, HashEntry(reversal))
+	fTest(kk.myEntries[3].ReversedBy, 5)
+	fTest(kk.myInv[1001], Inventory{{2, 10, 100}, {3, 5, 100}, {3, 5, 100}})
+	fTest(kk.myInv[2001], Inventory{{3, 700, 700}})
+	fTest(kk.myInv[3001], nil)
+	fTest(kk.myInv[-4001], nil)
+
+	_, err = ReverseEntry(&kk, 4, 6)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrEntryIsAlreadyReversed : the entry with ID 4 is already reversed by the entry with ID 5"))
+
+	_, err = ReverseEntry(&kk, 99, 6)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrEntryNotFound : there is no entry with ID 99 in the journal"))
+
+	// the cash layer of the first entry was merged by the WAC outflows so only the totals are taken out
+	reversal, err = ReverseEntry(&kk, 3, 6)
+	fTest(err, nil)
+	fTest(reversal.DoubleEntry, DoubleEntry{
+		{NONE, 1001, 10, 200},
+		{INFLOW, 2001, 200, 200},
+	})
+	fTest(reversal.Layers, AccountIDAndInventory{1001: Inventory{{3, 10, 200}}})
+	fTest(kk.myInv[1001], Inventory{{2, 10, 100}})
+	fTest(kk.myInv[2001], Inventory{{3, 700, 700}, {6, 200, 200}})
+
+	myInvExpected := kk.myInv
+	kk.myInv = make(AccountIDAndInventory)
+	fTest(CheckAllTheJournal(&kk), nil)
+	fTest(kk.myInv, myInvExpected)
+}
+
+func Test_ReverseEntryWithLayersOfTheSameTime(t *testing.T) {
+	var kk myDB
+	kk.myInv = make(AccountIDAndInventory)
+
+	entries := []AccountingEntry{
+		{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, -1001, 0, 1000}, {INFLOW, 2001, 1000, 1000}}},
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {WAC, 2001, 100, 100}}},
+		{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 300}, {WAC, 2001, 300, 300}}},
+		{TimeUnix: 6, DoubleEntry: DoubleEntry{{FIFO, 1001, 10, 100}, {INFLOW, 3001, 10, 100}}},
+	}
+	for _, entry := range entries {
+		_, err := AddToJournal(entry, &kk)
+		fTest(err, nil)
+	}
+
+	// the restored layer is not merged with the layer of the same time
+	_, err := ReverseEntry(&kk, 4, 7)
+	fTest(err, nil)
+	fTest(kk.myInv[1001], Inventory{{5, 10, 300}, {5, 10, 100}})
+
+	_, err = AddToJournal(AccountingEntry{TimeUnix: 8, DoubleEntry: DoubleEntry{{HIFO, 1001, 10, 300}, {INFLOW, 3001, 10, 300}}}, &kk)
+	fTest(err, nil)
+	fTest(kk.myInv[1001], Inventory{{5, 10, 100}})
+
+	myInvExpected := kk.myInv
+	kk.myInv = make(AccountIDAndInventory)
+	fTest(CheckAllTheJournal(&kk), nil)
+	fTest(kk.myInv, myInvExpected)
+}
@@ -0,0 +1,110 @@
+package accounting
+
+import (
+	"maps"
+	"slices"
+)
+
+// Violation is a problem of an entry found by ValidateEntry.
+type Violation struct {
+	LineIndex int // the index of the line in the DoubleEntry, -1 if the violation is about the whole entry
+	AccountID     // the account of the line, zero if the violation is about the whole entry
+	Err       error
+}
+
+// ValidateEntry checks an entry without its inventories and returns every violation it finds,
+// so all the problems of the entry can be fixed in one pass.
+//
+// Parameters:
+//   - lastTimeUnix: The timestamp of the last entry for chronological validation
+//   - entry: The accounting entry to be validated
+//
+// The violations are in this order:
+//   - The timestamp is not positive or is before the last entry
+//   - For every line in order: a wrong cost flow type, a negative quantity or amount,
+//     a duplicate account, a line with zero quantity and amount, a line that needs cost flow type NONE
+//     and layers that are negative, that don't add up to the line or that an INFLOW line adds after the time of the entry
+//   - Metadata of an account that has no line, in the order of the account IDs
+//   - Layers of an account that has no line, in the order of the account IDs
+//   - Debit not equal credit
+//
+// An entry without violations can still fail in CheckAndProcessDoubleEntry because of its inventories,
+// like when there is not enough quantity to take out.
+//
+// Returns nil if the entry has no violation.
+func ValidateEntry(lastTimeUnix TimeUnix, entry AccountingEntry) []Violation {
+	var violations []Violation
+	add := func(lineIndex int, ID AccountID, err error) {
+		violations = append(violations, Violation{lineIndex, ID, err})
+	}
+
+	if entry.TimeUnix <= 0 || entry.TimeUnix < lastTimeUnix {
+		add(-1, 0, newError(ErrTimeShouldBeBigger, "time should be bigger"))
+	}
+
+	totalDebit := Amount(0)
+	totalCredit := Amount(0)
+	accounts := make(map[AccountID]bool)
+	for i, single := range entry.DoubleEntry {
+		ID := single.AccountID
+		if single.CostFlowType >= TheNumberOfCostFlowTypes {
+			add(i, ID, newError(ErrTheCostFlowTypeIsWrong, "the cost flow type is wrong"))
+		}
+		if !(single.Amount >= 0) || !(single.Quantity >= 0) { // a NaN is not positive too
+			add(i, ID, newError(ErrTheQuantityAndAmountShouldBeBothPositive, "the quantity and amount should be both positive for account ID %v", ID))
+		}
+		if accounts[ID] {
+			add(i, ID, newError(ErrDuplicateAccountInEntry, "duplicate account ID %v in entry", ID))
+		}
+		accounts[ID] = true
+
+		if single.Quantity == 0 && single.Amount == 0 {
+			add(i, ID, newError(ErrQuantityAndAmountAreZero, "you can't enter both quantity and amount as zeros for account ID %v", ID))
+		} else if layers := entry.Layers[ID]; layers != nil {
+			for _, layer := range layers {
+				if !(layer.Quantity >= 0) || !(layer.Amount >= 0) {
+					add(i, ID, newError(ErrTheQuantityAndAmountShouldBeBothPositive, "the quantity and amount should be both positive for account ID %v", ID))
+					break
+				}
+			}
+			totalQuantity, totalAmount := GetTotalInventory(layers)
+			if totalQuantity != single.Quantity || totalAmount != single.Amount {
+				add(i, ID, newError(ErrLayersMismatch, "the layers of account ID %v add up to quantity = %v and amount = %v but the line has quantity = %v and amount = %v", ID, totalQuantity, totalAmount, single.Quantity, single.Amount))
+			}
+			if single.CostFlowType == INFLOW {
+				for _, layer := range layers {
+					if layer.TimeUnix > entry.TimeUnix {
+						add(i, ID, newError(ErrLayerAfterEntry, "the layer %v of account ID %v is after the time %v of the entry", layer, ID, entry.TimeUnix))
+						break
+					}
+				}
+			}
+		} else if single.CostFlowType != INFLOW && single.CostFlowType != NONE && (single.Quantity == 0 || single.Amount == 0) {
+			add(i, ID, fErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero(ID))
+		}
+
+		if GetStatus(single.CostFlowType, ID) {
+			totalDebit += single.Amount
+		} else {
+			totalCredit += single.Amount
+		}
+	}
+
+	for _, ID := range slices.Sorted(maps.Keys(entry.LinesMetadata)) {
+		if !accounts[ID] {
+			add(-1, ID, newError(ErrMetadataWithoutLine, "there is metadata for account ID %v but the entry has no line for it", ID))
+		}
+	}
+
+	for _, ID := range slices.Sorted(maps.Keys(entry.Layers)) {
+		if !accounts[ID] {
+			add(-1, ID, newError(ErrLayersWithoutLine, "there are layers for account ID %v but the entry has no line for it", ID))
+		}
+	}
+
+	if totalDebit != totalCredit {
+		add(-1, 0, newError(ErrDebitNotEqualCredit, "debit not equal credit and debit = %v , credit = %v and debit-credit = %v", totalDebit, totalCredit, totalDebit-totalCredit))
+	}
+
+	return violations
+}
@@ -0,0 +1,122 @@
+package accounting
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+	"github.com/HashemJaafar7/testutils"
+)
+
+func Test_ValidateEntry(t *testing.T) {
+	type input struct {
+		LastTimeUnix    TimeUnix
+		AccountingEntry AccountingEntry
+	}
+	type violation struct {
+		LineIndex int
+		AccountID AccountID
+		err       error
+	}
+	type output struct {
+		violations []violation
+	}
+	tests := []struct {
+		line   string
+		input  input
+		output output
+	}{
+		{
+			line: testutils.GetLine(),
+			input: input{
+				LastTimeUnix: 1,
+				AccountingEntry: AccountingEntry{
+					TimeUnix:    1,
+					DoubleEntry: DoubleEntry{{INFLOW, 1, 10, 100}, {INFLOW, -1, 10, 100}},
+				},
+			},
+			output: output{
+				violations: nil,
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				LastTimeUnix: 5,
+				AccountingEntry: AccountingEntry{
+					TimeUnix: 4,
+					DoubleEntry: DoubleEntry{
+						{INFLOW, 1, 10, 100},
+						{TheNumberOfCostFlowTypes, 2, 10, 100},
+						{INFLOW, 3, -10, 100},
+						{INFLOW, 1, 10, 100},
+						{INFLOW, -1, 0, 0},
+						{FIFO, 4, 10, 0},
+					},
+					LinesMetadata: map[AccountID]Metadata{9: {}, 1: {}, 8: {}},
+				},
+			},
+			output: output{
+				violations: []violation{
+					{-1, 0, fmt.Errorf("ErrTimeShouldBeBigger : time should be bigger")},
+					{1, 2, fmt.Errorf("ErrTheCostFlowTypeIsWrong : the cost flow type is wrong")},
+					{2, 3, fmt.Errorf("ErrTheQuantityAndAmountShouldBeBothPositive : the quantity and amount should be both positive for account ID 3")},
+					{3, 1, fmt.Errorf("ErrDuplicateAccountInEntry : duplicate account ID 1 in entry")},
+					{4, -1, fmt.Errorf("ErrQuantityAndAmountAreZero : you can't enter both quantity and amount as zeros for account ID -1")},
+					{5, 4, fmt.Errorf("ErrYouShouldUseCostFlowTypeNONEIfYouHaveQuantityOrAmountZero : you should to use cost flow type NONE because your quantity or amount is zero for account ID 4")},
+					{-1, 8, fmt.Errorf("ErrMetadataWithoutLine : there is metadata for account ID 8 but the entry has no line for it")},
+					{-1, 9, fmt.Errorf("ErrMetadataWithoutLine : there is metadata for account ID 9 but the entry has no line for it")},
+					{-1, 0, fmt.Errorf("ErrDebitNotEqualCredit : debit not equal credit and debit = 300 , credit = 100 and debit-credit = 200")},
+				},
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				LastTimeUnix: 1,
+				AccountingEntry: AccountingEntry{
+					TimeUnix:    2,
+					DoubleEntry: DoubleEntry{{INFLOW, 1, 15, 200}, {FIFO, 2, 15, 200}},
+					Layers: AccountIDAndInventory{
+						1: Inventory{{1, 10, 100}, {2, 5, -100}},
+						2: Inventory{{1, 15, 150}},
+					},
+				},
+			},
+			output: output{
+				violations: []violation{
+					{0, 1, fmt.Errorf("ErrTheQuantityAndAmountShouldBeBothPositive : the quantity and amount should be both positive for account ID 1")},
+					{0, 1, fmt.Errorf("ErrLayersMismatch : the layers of account ID 1 add up to quantity = 15 and amount = 0 but the line has quantity = 15 and amount = 200")},
+					{1, 2, fmt.Errorf("ErrLayersMismatch : the layers of account ID 2 add up to quantity = 15 and amount = 150 but the line has quantity = 15 and amount = 200")},
+				},
+			},
+		},
+		{
+			line: testutils.GetLine(),
+			input: input{
+				LastTimeUnix: 1,
+				AccountingEntry: AccountingEntry{
+					TimeUnix:    2,
+					DoubleEntry: DoubleEntry{{INFLOW, 1, 15, 200}, {INFLOW, -1, 15, 200}},
+					Layers: AccountIDAndInventory{
+						1: Inventory{{1, 10, 100}, {3, 5, 100}},
+						7: Inventory{{1, 15, 150}},
+					},
+				},
+			},
+			output: output{
+				violations: []violation{
+					{0, 1, fmt.Errorf("ErrLayerAfterEntry : the layer {3 5 100} of account ID 1 is after the time 2 of the entry")},
+					{-1, 7, fmt.Errorf("ErrLayersWithoutLine : there are layers for account ID 7 but the entry has no line for it")},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var output output
+		for _, v := range ValidateEntry(tt.input.LastTimeUnix, tt.input.AccountingEntry) {
+			output.violations = append(output.violations, violation{v.LineIndex, v.AccountID, goerrors.NormalizeTheError(v.Err)})
+		}
+		testutils.TestCase("+v", tt.line, tt.input, tt.output, output)
+	}
+}
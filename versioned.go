@@ -0,0 +1,59 @@
+package accounting
+
+// Version is the version of the inventory of an account, it increases by one every time the inventory is written.
+type Version uint64
+
+// VersionedDB is a DB for many processes that post to the same journal with optimistic concurrency.
+// GetInventoryVersion returns the inventory of the account with its version, zero if the account has no inventory.
+// SetEntryIfVersions writes the entry and the inventories in one transaction only if there is no entry
+// with the ID of the entry yet and every inventory still has its version in the map of versions,
+// then the version of every written inventory increases by one.
+// Otherwise it writes nothing and returns false.
+type VersionedDB interface {
+	DB
+	GetInventoryVersion(AccountID) (Inventory, Version, error)
+	SetEntryIfVersions(AccountingEntry, AccountIDAndInventory, map[AccountID]Version) (bool, error)
+}
+
+// AddToJournalVersioned is AddToJournal for a journal that other processes post to at the same time.
+//
+// Parameters:
+//   - entry: The AccountingEntry to be added to the journal
+//   - dbCommand: The storage of the journal and the inventories
+//   - retries: How many times to post the entry again if another writer changed its inventories or the journal first
+//
+// The inventories are read with their versions and the entry and the new inventories are written
+// together by SetEntryIfVersions. If another writer got there first, the entry is processed again
+// against the new inventories and the new last entry, so it can also fail like AddToJournal,
+// for example because the time of the entry is now before the last entry.
+//
+// Returns the ID of the new entry, or an Error with the name ErrVersionConflict if all the tries conflicted.
+func AddToJournalVersioned(entry AccountingEntry, dbCommand VersionedDB, retries int) (EntryID, error) {
+	for range retries + 1 {
+		IDAndInventory := make(AccountIDAndInventory)
+		versions := make(map[AccountID]Version)
+		for _, single := range entry.DoubleEntry {
+			inv, version, err := dbCommand.GetInventoryVersion(single.AccountID)
+			if err != nil {
+				return 0, err
+			}
+			IDAndInventory[single.AccountID] = inv
+			versions[single.AccountID] = version
+		}
+
+		newEntry, IDAndInventory, err := processNewEntry(entry, IDAndInventory, dbCommand)
+		if err != nil || IDAndInventory == nil {
+			return newEntry.ID, err
+		}
+
+		ok, err := dbCommand.SetEntryIfVersions(newEntry, IDAndInventory, versions)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return newEntry.ID, nil
+		}
+	}
+
+	return 0, newError(ErrVersionConflict, "another writer changed the inventories or the journal in all the %v tries", retries+1)
+}
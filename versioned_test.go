@@ -0,0 +1,90 @@
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/HashemJaafar7/goerrors"
+)
+
+// myVersionedDB is myDB with versions, before every SetEntryIfVersions it lets another writer post an entry.
+type myVersionedDB struct {
+	*myDB
+	versions    map[AccountID]Version
+	otherWriter []AccountingEntry
+}
+
+func (s *myVersionedDB) GetInventoryVersion(key AccountID) (Inventory, Version, error) {
+	inv, err := s.GetInventory(key)
+	return inv, s.versions[key], err
+}
+
+func (s *myVersionedDB) SetEntryIfVersions(entry AccountingEntry, IDAndInventory AccountIDAndInventory, versions map[AccountID]Version) (bool, error) {
+	if len(s.otherWriter) != 0 {
+		other := s.otherWriter[0]
+		s.otherWriter = s.otherWriter[1:]
+		other, err := addToJournal(other, s.myDB)
+		if err != nil {
+			return false, err
+		}
+		for _, single := range other.DoubleEntry {
+			s.versions[single.AccountID]++
+		}
+	}
+
+	if _, ok, _ := s.GetEntry(entry.ID); ok {
+		return false, nil
+	}
+	for ID, version := range versions {
+		if s.versions[ID] != version {
+			return false, nil
+		}
+	}
+
+	s.SetEntry(entry)
+	maps.Copy(s.myInv, IDAndInventory)
+	for ID := range IDAndInventory {
+		s.versions[ID]++
+	}
+	return true, nil
+}
+
+func Test_AddToJournalVersioned(t *testing.T) {
+	db := &myVersionedDB{myDB: &myDB{myInv: make(AccountIDAndInventory)}, versions: make(map[AccountID]Version)}
+
+	ID, err := AddToJournalVersioned(AccountingEntry{TimeUnix: 1, DoubleEntry: DoubleEntry{{INFLOW, 1001, 10, 100}, {INFLOW, -1001, 10, 100}}}, db, 0)
+	fTest(err, nil)
+	fTest(ID, 1)
+	fTest(db.versions, map[AccountID]Version{1001: 1, -1001: 1})
+
+	// the other writer takes out of the same account first, so the entry is processed again against what is left
+	db.otherWriter = []AccountingEntry{{TimeUnix: 2, DoubleEntry: DoubleEntry{{FIFO, 1001, 4, 40}, {INFLOW, 3001, 4, 40}}}}
+	ID, err = AddToJournalVersioned(AccountingEntry{TimeUnix: 2, DoubleEntry: DoubleEntry{{FIFO, 1001, 6, 60}, {INFLOW, 3001, 6, 60}}}, db, 1)
+	fTest(err, nil)
+	fTest(ID, 3)
+	fTest(db.myEntries[2].Sequence, 1)
+	fTest(db.myInv[1001], nil)
+	fTest(db.versions, map[AccountID]Version{1001: 3, -1001: 1, 3001: 2})
+
+	// the other writer only posts to other accounts but it takes the ID
+	db.otherWriter = []AccountingEntry{{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, 1002, 1, 1}, {INFLOW, -1002, 1, 1}}}}
+	ID, err = AddToJournalVersioned(AccountingEntry{TimeUnix: 3, DoubleEntry: DoubleEntry{{INFLOW, 1001, 1, 1}, {INFLOW, -1001, 1, 1}}}, db, 1)
+	fTest(err, nil)
+	fTest(ID, 5)
+
+	db.otherWriter = []AccountingEntry{
+		{TimeUnix: 4, DoubleEntry: DoubleEntry{{INFLOW, 1002, 1, 1}, {INFLOW, -1002, 1, 1}}},
+		{TimeUnix: 4, DoubleEntry: DoubleEntry{{INFLOW, 1002, 1, 1}, {INFLOW, -1002, 1, 1}}},
+	}
+	_, err = AddToJournalVersioned(AccountingEntry{TimeUnix: 4, DoubleEntry: DoubleEntry{{INFLOW, 1001, 1, 1}, {INFLOW, -1001, 1, 1}}}, db, 1)
+	fTest(goerrors.NormalizeTheError(err), fmt.Errorf("ErrVersionConflict : another writer changed the inventories or the journal in all the 2 tries"))
+	fTest(errors.Is(err, ErrConflict), true)
+	fTest(len(db.myEntries), 7)
+
+	// the other writer posts a later time, then the entry is not valid anymore
+	db.otherWriter = []AccountingEntry{{TimeUnix: 6, DoubleEntry: DoubleEntry{{INFLOW, 1001, 1, 1}, {INFLOW, -1001, 1, 1}}}}
+	_, err = AddToJournalVersioned(AccountingEntry{TimeUnix: 5, DoubleEntry: DoubleEntry{{INFLOW, 1001, 1, 1}, {INFLOW, -1001, 1, 1}}}, db, 3)
+	fTest(errors.Is(err, &Error{Name: ErrTimeShouldBeBigger}), true)
+}